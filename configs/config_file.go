@@ -1,12 +1,423 @@
 package configs
 
-var PostgresPreferences postgresPreferencesStruct
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
-func init() {
-	PostgresPreferences = postgresPreferencesStruct{
-		User: "docker",
-		Password: "docker",
-		DBName: "docker",
-		Port: "5432",
+	"gopkg.in/yaml.v2"
+)
+
+const configPathEnv = "FORUM_CONFIG"
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+var rateLimitStores = []string{"memory", "postgres"}
+var logLevels = []string{"debug", "info", "warn", "error"}
+var tracingExporters = []string{"none", "stdout", "otlp"}
+
+type setter func(value string) error
+
+type binding struct {
+	flag  string
+	env   string
+	usage string
+	set   setter
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":5000",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+			RequestTimeout:  10 * time.Second,
+			MaxBodyBytes:    4 << 20,
+		},
+		Postgres: PostgresConfig{
+			Host:                   "localhost",
+			Port:                   5432,
+			User:                   "docker",
+			Password:               "docker",
+			DBName:                 "docker",
+			SSLMode:                "disable",
+			MaxConnections:         100,
+			MinConnections:         0,
+			AcquireTimeout:         0,
+			ConnectTimeout:         5 * time.Second,
+			MaxConnLifetime:        time.Hour,
+			MaxConnIdleTime:        30 * time.Minute,
+			HealthCheckPeriod:      time.Minute,
+			StatementCacheCapacity: 512,
+		},
+		Auth: AuthConfig{
+			SessionTTL: 24 * time.Hour,
+		},
+		Events: EventsConfig{
+			Heartbeat: 15 * time.Second,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Store: "memory",
+			Groups: map[string]RateLimitGroup{
+				"writes": {Rate: 5, Burst: 20, Routes: []string{"thread_create", "posts_create", "thread_vote"}},
+			},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Access: true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, an optional YAML file, FORUM_*
+// environment variables and command line flags, in increasing precedence.
+// Arguments left after the flags are returned untouched.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+	bindings := cfg.bindings()
+
+	fs := flag.NewFlagSet("forum", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(configPathEnv), "path to a YAML config file")
+	raw := make(map[string]*string, len(bindings))
+	for _, b := range bindings {
+		raw[b.flag] = fs.String(b.flag, "", fmt.Sprintf("%s (env %s)", b.usage, b.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.readFile(*configPath); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	for _, b := range bindings {
+		value, ok := os.LookupEnv(b.env)
+		if !ok {
+			continue
+		}
+		if err := b.set(value); err != nil {
+			return Config{}, nil, fmt.Errorf("env %s: %v", b.env, err)
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, b := range bindings {
+			if b.flag != f.Name || flagErr != nil {
+				continue
+			}
+			if err := b.set(*raw[b.flag]); err != nil {
+				flagErr = fmt.Errorf("flag -%s: %v", b.flag, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return Config{}, nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %v", err)
+	}
+	// Strict decoding takes keys already in a map for duplicates, so the
+	// groups are decoded apart and merged with the defaults by name. A file
+	// group without routes keeps the default ones; routes: [] turns it off.
+	groups := c.RateLimit.Groups
+	c.RateLimit.Groups = nil
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		c.RateLimit.Groups = groups
+		return fmt.Errorf("config file %s: %v", path, err)
+	}
+	for name, group := range groups {
+		override, ok := c.RateLimit.Groups[name]
+		if !ok {
+			if c.RateLimit.Groups == nil {
+				c.RateLimit.Groups = make(map[string]RateLimitGroup)
+			}
+			c.RateLimit.Groups[name] = group
+			continue
+		}
+		if override.Routes == nil {
+			override.Routes = group.Routes
+			c.RateLimit.Groups[name] = override
+		}
+	}
+	return nil
+}
+
+func (c *Config) bindings() []binding {
+	return []binding{
+		{"addr", "FORUM_SERVER_ADDR", "listen address", stringSetter(&c.Server.Addr)},
+		{"read-timeout", "FORUM_SERVER_READ_TIMEOUT", "HTTP read timeout", durationSetter(&c.Server.ReadTimeout)},
+		{"write-timeout", "FORUM_SERVER_WRITE_TIMEOUT", "HTTP write timeout", durationSetter(&c.Server.WriteTimeout)},
+		{"idle-timeout", "FORUM_SERVER_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", durationSetter(&c.Server.IdleTimeout)},
+		{"shutdown-timeout", "FORUM_SERVER_SHUTDOWN_TIMEOUT", "time to drain requests on SIGTERM", durationSetter(&c.Server.ShutdownTimeout)},
+		{"request-timeout", "FORUM_SERVER_REQUEST_TIMEOUT", "default per-request deadline, 0 disables", durationSetter(&c.Server.RequestTimeout)},
+		{"max-body-bytes", "FORUM_SERVER_MAX_BODY_BYTES", "largest accepted request body", int64Setter(&c.Server.MaxBodyBytes)},
+		{"validate-responses", "FORUM_SERVER_VALIDATE_RESPONSES", "log responses that do not match the OpenAPI document", boolSetter(&c.Server.ValidateResponses)},
+		{"cursor-secret", "FORUM_SERVER_CURSOR_SECRET", "key signing pagination cursors, random when empty", stringSetter(&c.Server.CursorSecret)},
+		{"db-host", "FORUM_DB_HOST", "postgres host", stringSetter(&c.Postgres.Host)},
+		{"db-port", "FORUM_DB_PORT", "postgres port", intSetter(&c.Postgres.Port)},
+		{"db-user", "FORUM_DB_USER", "postgres user", stringSetter(&c.Postgres.User)},
+		{"db-password", "FORUM_DB_PASSWORD", "postgres password", stringSetter(&c.Postgres.Password)},
+		{"db-name", "FORUM_DB_NAME", "postgres database name", stringSetter(&c.Postgres.DBName)},
+		{"db-sslmode", "FORUM_DB_SSLMODE", "postgres sslmode", stringSetter(&c.Postgres.SSLMode)},
+		{"db-max-connections", "FORUM_DB_MAX_CONNECTIONS", "connection pool size", intSetter(&c.Postgres.MaxConnections)},
+		{"db-min-connections", "FORUM_DB_MIN_CONNECTIONS", "connections kept open while idle", intSetter(&c.Postgres.MinConnections)},
+		{"db-acquire-timeout", "FORUM_DB_ACQUIRE_TIMEOUT", "pool acquire timeout, 0 waits until the request deadline", durationSetter(&c.Postgres.AcquireTimeout)},
+		{"db-connect-timeout", "FORUM_DB_CONNECT_TIMEOUT", "timeout for dialing a new connection", durationSetter(&c.Postgres.ConnectTimeout)},
+		{"db-max-conn-lifetime", "FORUM_DB_MAX_CONN_LIFETIME", "recycle connections older than this", durationSetter(&c.Postgres.MaxConnLifetime)},
+		{"db-max-conn-idle-time", "FORUM_DB_MAX_CONN_IDLE_TIME", "close connections idle longer than this", durationSetter(&c.Postgres.MaxConnIdleTime)},
+		{"db-health-check-period", "FORUM_DB_HEALTH_CHECK_PERIOD", "interval between pool health checks", durationSetter(&c.Postgres.HealthCheckPeriod)},
+		{"db-statement-cache", "FORUM_DB_STATEMENT_CACHE", "prepared statements cached per connection, 0 disables", intSetter(&c.Postgres.StatementCacheCapacity)},
+		{"migrate-on-start", "FORUM_MIGRATE_ON_START", "apply pending migrations before serving", boolSetter(&c.Migrations.OnStart)},
+		{"auth-enforce", "FORUM_AUTH_ENFORCE", "require a session to post, vote or edit a profile", boolSetter(&c.Auth.Enforce)},
+		{"auth-session-ttl", "FORUM_AUTH_SESSION_TTL", "lifetime of a login session", durationSetter(&c.Auth.SessionTTL)},
+		{"auth-admins", "FORUM_AUTH_ADMINS", "comma separated nicknames of site admins", listSetter(&c.Auth.Admins)},
+		{"events-heartbeat", "FORUM_EVENTS_HEARTBEAT", "keep-alive interval of event streams", durationSetter(&c.Events.Heartbeat)},
+		{"events-lifetime", "FORUM_EVENTS_LIFETIME", "reconnect event streams after this long, 0 follows write-timeout", durationSetter(&c.Events.Lifetime)},
+		{"idempotency-ttl", "FORUM_IDEMPOTENCY_TTL", "how long Idempotency-Key responses are replayed", durationSetter(&c.Idempotency.TTL)},
+		{"ratelimit-enabled", "FORUM_RATELIMIT_ENABLED", "apply the rate limit groups", boolSetter(&c.RateLimit.Enabled)},
+		{"ratelimit-store", "FORUM_RATELIMIT_STORE", "where token buckets live: memory or postgres", stringSetter(&c.RateLimit.Store)},
+		{"ratelimit-trust-proxy", "FORUM_RATELIMIT_TRUST_PROXY", "take client addresses from X-Forwarded-For", boolSetter(&c.RateLimit.TrustProxy)},
+		{"log-level", "FORUM_LOG_LEVEL", "least severe log level: debug, info, warn or error", stringSetter(&c.Log.Level)},
+		{"log-access", "FORUM_LOG_ACCESS", "log every request", boolSetter(&c.Log.Access)},
+		{"tracing-exporter", "FORUM_TRACING_EXPORTER", "where spans go: none, stdout or otlp", stringSetter(&c.Tracing.Exporter)},
+		{"tracing-endpoint", "FORUM_TRACING_ENDPOINT", "host:port of the OTLP/HTTP collector", stringSetter(&c.Tracing.Endpoint)},
+		{"tracing-insecure", "FORUM_TRACING_INSECURE", "send spans to the collector without TLS", boolSetter(&c.Tracing.Insecure)},
+		{"tracing-sample-ratio", "FORUM_TRACING_SAMPLE_RATIO", "share of new traces that are recorded, 0 to 1", floatSetter(&c.Tracing.SampleRatio)},
+		{"service-allow-clear", "FORUM_SERVICE_ALLOW_CLEAR", "enable the endpoints that wipe data", boolSetter(&c.Service.AllowClear)},
+		{"service-admin-token", "FORUM_SERVICE_ADMIN_TOKEN", "token the clear endpoints require in X-Admin-Token", stringSetter(&c.Service.AdminToken)},
+	}
+}
+
+func (c Config) Validate() error {
+	var problems []string
+
+	if c.Server.Addr == "" {
+		problems = append(problems, "server.addr is empty")
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		problems = append(problems, "server timeouts must not be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdown_timeout must be positive")
+	}
+	if c.Server.RequestTimeout < 0 {
+		problems = append(problems, "server.request_timeout must not be negative")
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		problems = append(problems, "server.max_body_bytes must be positive")
+	}
+	for route, timeout := range c.Server.RouteTimeouts {
+		if timeout < 0 {
+			problems = append(problems, fmt.Sprintf("server.route_timeouts.%s must not be negative", route))
+		}
+	}
+
+	if c.Postgres.Host == "" {
+		problems = append(problems, "postgres.host is empty")
+	}
+	if c.Postgres.Port <= 0 || c.Postgres.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("postgres.port %d is out of range", c.Postgres.Port))
+	}
+	if c.Postgres.User == "" {
+		problems = append(problems, "postgres.user is empty")
+	}
+	if c.Postgres.DBName == "" {
+		problems = append(problems, "postgres.dbname is empty")
+	}
+	if !contains(sslModes, c.Postgres.SSLMode) {
+		problems = append(problems, fmt.Sprintf("postgres.sslmode %q is not one of %s",
+			c.Postgres.SSLMode, strings.Join(sslModes, ", ")))
+	}
+	if c.Postgres.MaxConnections < 2 {
+		problems = append(problems, "postgres.max_connections must be at least 2")
+	}
+	if c.Postgres.MinConnections < 0 || c.Postgres.MinConnections > c.Postgres.MaxConnections {
+		problems = append(problems, "postgres.min_connections must be between 0 and max_connections")
+	}
+	if c.Postgres.AcquireTimeout < 0 || c.Postgres.ConnectTimeout < 0 || c.Postgres.MaxConnLifetime < 0 || c.Postgres.MaxConnIdleTime < 0 {
+		problems = append(problems, "postgres connection timeouts must not be negative")
+	}
+	if c.Postgres.HealthCheckPeriod <= 0 {
+		problems = append(problems, "postgres.health_check_period must be positive")
+	}
+	if c.Postgres.StatementCacheCapacity < 0 {
+		problems = append(problems, "postgres.statement_cache_capacity must not be negative")
+	}
+
+	if c.Auth.SessionTTL <= 0 {
+		problems = append(problems, "auth.session_ttl must be positive")
+	}
+
+	if c.Events.Heartbeat <= 0 {
+		problems = append(problems, "events.heartbeat must be positive")
+	}
+	if c.Events.Lifetime < 0 {
+		problems = append(problems, "events.lifetime must not be negative")
+	}
+	if c.Idempotency.TTL <= 0 {
+		problems = append(problems, "idempotency.ttl must be positive")
+	}
+
+	if !contains(rateLimitStores, c.RateLimit.Store) {
+		problems = append(problems, fmt.Sprintf("ratelimit.store %q is not one of %s",
+			c.RateLimit.Store, strings.Join(rateLimitStores, ", ")))
+	}
+	for name, group := range c.RateLimit.Groups {
+		if len(group.Routes) != 0 && (group.Rate <= 0 || group.Burst < 1) {
+			problems = append(problems, fmt.Sprintf("ratelimit.groups.%s needs a positive rate and burst", name))
+		}
+	}
+
+	if !contains(logLevels, c.Log.Level) {
+		problems = append(problems, fmt.Sprintf("log.level %q is not one of %s",
+			c.Log.Level, strings.Join(logLevels, ", ")))
+	}
+
+	if !contains(tracingExporters, c.Tracing.Exporter) {
+		problems = append(problems, fmt.Sprintf("tracing.exporter %q is not one of %s",
+			c.Tracing.Exporter, strings.Join(tracingExporters, ", ")))
+	}
+	if c.Tracing.Exporter == "otlp" && c.Tracing.Endpoint == "" {
+		problems = append(problems, "tracing.endpoint is empty")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if c.Service.AllowClear && c.Service.AdminToken == "" {
+		problems = append(problems, "service.admin_token is required when service.allow_clear is on")
+	}
+
+	if len(problems) != 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ConnString rounds connect_timeout up to whole seconds: libpq takes 0 for
+// no timeout at all.
+func (p PostgresConfig) ConnString() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d",
+		connValue(p.Host), p.Port, connValue(p.User), connValue(p.Password), connValue(p.DBName),
+		connValue(p.SSLMode), int(math.Ceil(p.ConnectTimeout.Seconds())))
+}
+
+// connValue quotes a keyword/value connection string value, so passwords
+// with spaces, quotes or backslashes survive.
+func connValue(value string) string {
+	return "'" + connEscaper.Replace(value) + "'"
+}
+
+var connEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func stringSetter(p *string) setter {
+	return func(value string) error {
+		*p = value
+		return nil
+	}
+}
+
+func intSetter(p *int) setter {
+	return func(value string) error {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func int64Setter(p *int64) setter {
+	return func(value string) error {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func floatSetter(p *float64) setter {
+	return func(value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func boolSetter(p *bool) setter {
+	return func(value string) error {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func durationSetter(p *time.Duration) setter {
+	return func(value string) error {
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+func listSetter(p *[]string) setter {
+	return func(value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*p = items
+		return nil
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
 	}
-}
\ No newline at end of file
+	return false
+}
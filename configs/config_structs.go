@@ -1,8 +1,132 @@
 package configs
 
-type postgresPreferencesStruct struct {
-	User string
-	Password string
-	DBName string
-	Port string
-}
\ No newline at end of file
+import "time"
+
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Postgres    PostgresConfig    `yaml:"postgres"`
+	Migrations  MigrationsConfig  `yaml:"migrations"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Events      EventsConfig      `yaml:"events"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	RateLimit   RateLimitConfig   `yaml:"ratelimit"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Service     ServiceConfig     `yaml:"service"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// RequestTimeout bounds every request context; RouteTimeouts overrides
+	// it per route name as registered in NewForumHandler.
+	RequestTimeout time.Duration            `yaml:"request_timeout"`
+	RouteTimeouts  map[string]time.Duration `yaml:"route_timeouts"`
+	MaxBodyBytes   int64                    `yaml:"max_body_bytes"`
+	// ValidateResponses checks every response against the OpenAPI document
+	// and logs mismatches. It costs a body copy per request.
+	ValidateResponses bool `yaml:"validate_responses"`
+	// CursorSecret signs pagination cursors. When empty a random key is
+	// generated, so cursors break on restart and across instances.
+	CursorSecret string `yaml:"cursor_secret"`
+}
+
+type PostgresConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DBName   string `yaml:"dbname"`
+	SSLMode  string `yaml:"sslmode"`
+	// AcquireTimeout bounds waiting for a free connection on top of the
+	// request deadline; zero leaves only the deadline.
+	AcquireTimeout         time.Duration `yaml:"acquire_timeout"`
+	MaxConnections         int           `yaml:"max_connections"`
+	MinConnections         int           `yaml:"min_connections"`
+	ConnectTimeout         time.Duration `yaml:"connect_timeout"`
+	MaxConnLifetime        time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime        time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod      time.Duration `yaml:"health_check_period"`
+	StatementCacheCapacity int           `yaml:"statement_cache_capacity"`
+}
+
+type MigrationsConfig struct {
+	OnStart bool `yaml:"on_start"`
+}
+
+type AuthConfig struct {
+	// Enforce rejects anonymous posts, threads, votes and profile edits.
+	// Off by default so clients without sessions keep working.
+	Enforce    bool          `yaml:"enforce"`
+	SessionTTL time.Duration `yaml:"session_ttl"`
+	// Admins are site administrators: owners of every forum.
+	Admins []string `yaml:"admins"`
+}
+
+type EventsConfig struct {
+	// Heartbeat is the interval of keep-alive comments on idle streams.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// Lifetime ends a stream so the client reconnects. Streams always end
+	// before server.write_timeout would cut them; 0 means only then.
+	Lifetime time.Duration `yaml:"lifetime"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long a retry with the same Idempotency-Key is answered
+	// from the stored response.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Store is "memory", private to the instance, or "postgres", shared by
+	// every instance at the cost of a transaction per limited request.
+	Store string `yaml:"store"`
+	// TrustProxy takes the client address from the last X-Forwarded-For
+	// entry. Only set it behind exactly one proxy that appends to the header.
+	TrustProxy bool `yaml:"trust_proxy"`
+	// Groups are merged by name with the defaults; a group overriding a
+	// default without routes keeps its routes, routes: [] turns it off.
+	Groups map[string]RateLimitGroup `yaml:"groups"`
+}
+
+// RateLimitGroup is a token bucket per client IP and per logged in user,
+// shared by the routes of the group.
+type RateLimitGroup struct {
+	// Rate is in requests per second.
+	Rate   float64  `yaml:"rate"`
+	Burst  int      `yaml:"burst"`
+	Routes []string `yaml:"routes"`
+}
+
+type LogConfig struct {
+	// Level is the least severe level written: debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Access writes a line per request with its status and duration.
+	Access bool `yaml:"access"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "stdout" for local debugging or "otlp" to send
+	// spans to a collector over OTLP/HTTP.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the host:port of the collector. The standard
+	// OTEL_EXPORTER_OTLP_* variables are honoured as well.
+	Endpoint string `yaml:"endpoint"`
+	// Insecure sends spans over plain HTTP, as to a collector on localhost.
+	Insecure bool `yaml:"insecure"`
+	// SampleRatio is the share of traces started here that are recorded.
+	// Traces continued from a sampled traceparent are always recorded.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type ServiceConfig struct {
+	// AllowClear enables the endpoints that wipe data. Keep it off in
+	// production.
+	AllowClear bool `yaml:"allow_clear"`
+	// AdminToken must come in X-Admin-Token with every clear request.
+	AdminToken string `yaml:"admin_token"`
+}
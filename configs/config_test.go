@@ -0,0 +1,68 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadShippedConfig(t *testing.T) {
+	if _, _, err := Load([]string{"-config", "config.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRateLimitGroupsMergeWithDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "ratelimit:\n  groups:\n    writes:\n      rate: 1\n      burst: 2\n    reads:\n      rate: 3\n      burst: 4\n      routes: [forum_details]\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Default().RateLimit.Groups["writes"].Routes
+	if writes := cfg.RateLimit.Groups["writes"]; writes.Rate != 1 || writes.Burst != 2 || !reflect.DeepEqual(writes.Routes, want) {
+		t.Errorf("writes is %+v, want the file's rate and burst with the default routes %v", writes, want)
+	}
+	if reads := cfg.RateLimit.Groups["reads"]; reads.Rate != 3 || len(reads.Routes) != 1 {
+		t.Errorf("reads is %+v, want the file's", reads)
+	}
+}
+
+func TestRateLimitGroupWithEmptyRoutesIsOff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "ratelimit:\n  groups:\n    writes:\n      rate: 1\n      burst: 2\n      routes: []\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writes := cfg.RateLimit.Groups["writes"]; len(writes.Routes) != 0 {
+		t.Errorf("writes is %+v, want no routes", writes)
+	}
+}
+
+func TestConnStringRoundsConnectTimeoutUp(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{0, "connect_timeout=0"},
+		{500 * time.Millisecond, "connect_timeout=1"},
+		{5 * time.Second, "connect_timeout=5"},
+		{5500 * time.Millisecond, "connect_timeout=6"},
+	}
+	for _, test := range tests {
+		got := PostgresConfig{ConnectTimeout: test.timeout}.ConnString()
+		if !strings.HasSuffix(got, test.want) {
+			t.Errorf("%v gives %q, want %s", test.timeout, got, test.want)
+		}
+	}
+}
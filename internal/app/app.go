@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"technopark-dbms-forum/configs"
+	"technopark-dbms-forum/internal/cursor"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/internal/events"
+	"technopark-dbms-forum/internal/health"
+	"technopark-dbms-forum/internal/idempotency"
+	"technopark-dbms-forum/internal/logging"
+	"technopark-dbms-forum/internal/metrics"
+	"technopark-dbms-forum/internal/migrations"
+	"technopark-dbms-forum/internal/openapi"
+	"technopark-dbms-forum/internal/ratelimit"
+	"technopark-dbms-forum/internal/tracing"
+
+	archiveRepo "technopark-dbms-forum/internal/archive/repository/postgres"
+	authHandlers "technopark-dbms-forum/internal/auth/delivery"
+	authInstrumented "technopark-dbms-forum/internal/auth/repository/instrumented"
+	authRepo "technopark-dbms-forum/internal/auth/repository/postgres"
+	authUseCase "technopark-dbms-forum/internal/auth/usecase"
+	forumHandlers "technopark-dbms-forum/internal/forum/delivery"
+	forumInstrumented "technopark-dbms-forum/internal/forum/repository/instrumented"
+	forumRepo "technopark-dbms-forum/internal/forum/repository/postgres"
+	forumUseCase "technopark-dbms-forum/internal/forum/usecase"
+	forumUseCaseInstrumented "technopark-dbms-forum/internal/forum/usecase/instrumented"
+	idempotencyRepo "technopark-dbms-forum/internal/idempotency/repository/postgres"
+	notificationInstrumented "technopark-dbms-forum/internal/notification/repository/instrumented"
+	notificationRepo "technopark-dbms-forum/internal/notification/repository/postgres"
+	notificationUseCase "technopark-dbms-forum/internal/notification/usecase"
+	rateLimitRepo "technopark-dbms-forum/internal/ratelimit/repository/postgres"
+)
+
+// tracingFlushTimeout bounds sending the last spans on exit.
+const tracingFlushTimeout = 5 * time.Second
+
+type App struct {
+	cfg    configs.Config
+	log    zerolog.Logger
+	pool   *pgxpool.Pool
+	server *http.Server
+	events *events.Hub
+}
+
+func New(cfg configs.Config, log zerolog.Logger) (*App, error) {
+	ctx := context.Background()
+	pool, err := database.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrations.New(pool)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	if cfg.Migrations.OnStart {
+		if err := migrateOnStart(ctx, migrator, log); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	cursors, err := cursor.NewSigner(cfg.Server.CursorSecret)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	stats := metrics.New(pool)
+	conn := database.AcquireTimeout(pool, cfg.Postgres.AcquireTimeout)
+
+	router := mux.NewRouter()
+	router.Use(tracing.Middleware())
+	router.Use(stats.Middleware())
+	if cfg.Server.ValidateResponses {
+		router.Use(spec.ValidationMiddleware(log))
+	}
+	router.Use(forumHandlers.TimeoutMiddleware(cfg.Server.RequestTimeout, routeTimeouts(cfg.Server.RouteTimeouts)))
+	router.Use(forumHandlers.BodyLimitMiddleware(cfg.Server.MaxBodyBytes, "forum_import"))
+	router.Use(forumHandlers.AdminMiddleware(cfg.Service.AllowClear, cfg.Service.AdminToken, "service_clear", "forum_clear"))
+	router.Use(forumHandlers.AdminMiddleware(cfg.Service.AdminToken != "", cfg.Service.AdminToken, "forum_export", "forum_import"))
+
+	authRepository := authInstrumented.NewAuthRepository(authRepo.NewPostgresAuthRepository(conn), stats)
+	authUsecase := authUseCase.NewAuthUsecase(authRepository, cfg.Auth)
+	router.Use(authHandlers.Middleware(authUsecase))
+	if cfg.RateLimit.Enabled {
+		router.Use(ratelimit.Middleware(rateLimitStore(cfg.RateLimit, conn), cfg.RateLimit, log))
+	}
+	router.Use(idempotency.Middleware(idempotencyRepo.NewPostgresIdempotencyStore(conn), cfg.Idempotency.TTL,
+		cfg.RateLimit.TrustProxy, "thread_create", "posts_create", "thread_vote"))
+
+	forumRepository := forumInstrumented.NewForumRepository(forumRepo.NewPostgresForumRepository(tracing.Conn(conn), log), stats)
+	forumUsecase := forumUseCaseInstrumented.NewForumUsecase(forumUseCase.NewForumUsecase(forumRepository, authUsecase, log))
+	hub := events.NewHub(eventsConfig(cfg), forumRepository.SelectPostRange, log)
+
+	notificationRepository := notificationInstrumented.NewNotificationRepository(
+		notificationRepo.NewPostgresNotificationRepository(conn), stats)
+	notificationUsecase := notificationUseCase.NewNotificationUsecase(notificationRepository, authUsecase)
+
+	Routes(router, Services{
+		Forum:        forumUsecase,
+		Auth:         authUsecase,
+		Notification: notificationUsecase,
+		Archive:      archiveRepo.NewPostgresArchiveStore(tracing.Conn(conn)),
+		Cursors:      cursors,
+		Events:       hub,
+		Health:       health.NewChecker(pool, migrator),
+		Pool:         pool,
+		Metrics:      stats,
+		Spec:         spec,
+		Log:          log,
+	})
+
+	if err := spec.CheckRoutes(router); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &App{
+		cfg:    cfg,
+		log:    log,
+		pool:   pool,
+		events: hub,
+		server: &http.Server{
+			Addr:         cfg.Server.Addr,
+			Handler:      logging.Middleware(log, cfg.Log.Access)(router),
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+			ErrorLog:     stdlog.New(log, "", 0),
+		},
+	}, nil
+}
+
+// Run serves HTTP until the listener fails or SIGINT/SIGTERM arrives, then
+// drains in-flight requests within the shutdown timeout and closes the pool.
+func (a *App) Run() error {
+	defer a.pool.Close()
+
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	go a.events.Listen(listenCtx, a.pool)
+	a.server.RegisterOnShutdown(a.events.Close)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		a.log.Info().Str("addr", a.cfg.Server.Addr).Msg("starting server")
+		serverErr <- a.server.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("server: %v", err)
+	case sig := <-stop:
+		a.log.Info().Str("signal", sig.String()).Msg("shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := a.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown: %v", err)
+	}
+	return nil
+}
+
+func rateLimitStore(cfg configs.RateLimitConfig, conn database.Conn) ratelimit.Store {
+	if cfg.Store == "postgres" {
+		return rateLimitRepo.NewPostgresRateLimitStore(conn)
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// routeTimeouts exempts the event streams from the request deadline unless
+// configured otherwise; events.lifetime bounds them instead.
+func routeTimeouts(configured map[string]time.Duration) map[string]time.Duration {
+	timeouts := map[string]time.Duration{"thread_events": 0, "forum_events": 0, "forum_export": 0, "forum_import": 0}
+	for route, timeout := range configured {
+		timeouts[route] = timeout
+	}
+	return timeouts
+}
+
+// eventsConfig ends streams a second before server.write_timeout would cut
+// them off mid-event.
+func eventsConfig(cfg configs.Config) configs.EventsConfig {
+	events := cfg.Events
+	if limit := cfg.Server.WriteTimeout - time.Second; cfg.Server.WriteTimeout > 0 {
+		if limit <= 0 {
+			limit = cfg.Server.WriteTimeout
+		}
+		if events.Lifetime == 0 || events.Lifetime > limit {
+			events.Lifetime = limit
+		}
+	}
+	return events
+}
+
+// Main is the whole process lifecycle shared by the binaries under cmd/.
+func Main(args []string) int {
+	cfg, rest, err := configs.Load(args)
+	if err != nil {
+		log.Error().Err(err).Msg("loading config")
+		return 2
+	}
+	logger, err := logging.New(cfg.Log)
+	if err != nil {
+		log.Error().Err(err).Msg("creating logger")
+		return 2
+	}
+	// httpio logs through the global logger when a response is written
+	// outside logging.Middleware.
+	log.Logger = logger
+
+	if len(rest) != 0 {
+		switch rest[0] {
+		case "migrate":
+			err = runMigrate(cfg, rest[1:])
+		case "export":
+			err = runExport(cfg, rest[1:])
+		case "import":
+			err = runImport(cfg, rest[1:])
+		default:
+			err = fmt.Errorf("unknown command %q", rest[0])
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("command", rest[0]).Msg("command failed")
+			return 1
+		}
+		return 0
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Error().Err(err).Msg("setting up tracing")
+		return 1
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error().Err(err).Msg("flushing spans")
+		}
+	}()
+
+	application, err := New(cfg, logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("starting")
+		return 1
+	}
+
+	if err := application.Run(); err != nil {
+		logger.Error().Err(err).Msg("serving")
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"technopark-dbms-forum/configs"
+	"technopark-dbms-forum/internal/archive"
+	"technopark-dbms-forum/internal/database"
+
+	archiveRepo "technopark-dbms-forum/internal/archive/repository/postgres"
+)
+
+const (
+	exportUsage = "usage: export <forum> [file]"
+	importUsage = "usage: import [-slug forum] [file]"
+)
+
+// runExport writes the archive to file, or to stdout without one.
+func runExport(cfg configs.Config, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf(exportUsage)
+	}
+
+	ctx := context.Background()
+	pool, err := database.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	out := os.Stdout
+	if len(args) == 2 && args[1] != "-" {
+		out, err = os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	buffered := bufio.NewWriter(out)
+	if err := archiveRepo.NewPostgresArchiveStore(pool).Export(ctx, args[0], archive.NewWriter(buffered)); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// runImport reads the archive from file, or from stdin without one.
+func runImport(cfg configs.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	slug := flags.String("slug", "", "create the forum under this slug instead of the exported one")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf(importUsage)
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf(importUsage)
+	}
+
+	ctx := context.Background()
+	pool, err := database.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	var in io.Reader = os.Stdin
+	if flags.NArg() == 1 && flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	summary, err := archiveRepo.NewPostgresArchiveStore(pool).Import(ctx, archive.NewReader(bufio.NewReader(in)), *slug)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported forum %s: %d threads, %d posts, %d votes, %d new users\n",
+		summary.Forum, summary.Threads, summary.Posts, summary.Votes, summary.Users)
+	return nil
+}
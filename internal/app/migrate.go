@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/configs"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/internal/migrations"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+func runMigrate(cfg configs.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	ctx := context.Background()
+	pool, err := database.NewPool(ctx, cfg.Postgres)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	migrator, err := migrations.New(pool)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("down: steps must be a positive number")
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+}
+
+func migrateOnStart(ctx context.Context, migrator *migrations.Migrator, log zerolog.Logger) error {
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Info().Int("version", migration.Version).Str("name", migration.Name).Msg("applied migration")
+	}
+	return err
+}
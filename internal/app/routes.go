@@ -0,0 +1,55 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/internal/archive"
+	"technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/cursor"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/internal/events"
+	"technopark-dbms-forum/internal/forum"
+	"technopark-dbms-forum/internal/health"
+	"technopark-dbms-forum/internal/metrics"
+	"technopark-dbms-forum/internal/notification"
+	"technopark-dbms-forum/internal/openapi"
+
+	archiveHandlers "technopark-dbms-forum/internal/archive/delivery"
+	authHandlers "technopark-dbms-forum/internal/auth/delivery"
+	forumHandlers "technopark-dbms-forum/internal/forum/delivery"
+	notificationHandlers "technopark-dbms-forum/internal/notification/delivery"
+)
+
+// Services serve the routes. Tests fill them with fakes to get the router
+// the application runs.
+type Services struct {
+	Forum        forum.ForumUseCase
+	Auth         auth.AuthUseCase
+	Notification notification.NotificationUseCase
+	Archive      archive.Store
+	Cursors      *cursor.Signer
+	Events       *events.Hub
+	Health       *health.Checker
+	Pool         *pgxpool.Pool
+	Metrics      *metrics.Metrics
+	Spec         *openapi.Spec
+	Log          zerolog.Logger
+}
+
+// Routes registers every route of the service on router.
+func Routes(router *mux.Router, s Services) {
+	authHandlers.NewAuthHandler(router, s.Auth)
+	forumHandlers.NewForumHandler(router, s.Forum, s.Auth, s.Cursors, s.Events, s.Log)
+	notificationHandlers.NewNotificationHandler(router, s.Notification)
+	archiveHandlers.NewArchiveHandler(router, s.Archive, s.Log)
+
+	router.HandleFunc("/healthz", s.Health.Liveness()).Methods(http.MethodGet).Name("healthz")
+	router.HandleFunc("/readyz", s.Health.Readiness()).Methods(http.MethodGet).Name("readyz")
+	router.HandleFunc("/api/service/diagnostics", s.Health.Diagnostics()).Methods(http.MethodGet).Name("service_diagnostics")
+	router.HandleFunc("/api/service/pool", database.StatsHandler(s.Pool)).Methods(http.MethodGet).Name("service_pool")
+	router.HandleFunc("/api/openapi.json", s.Spec.Handler()).Methods(http.MethodGet).Name("openapi")
+	router.Handle("/metrics", s.Metrics.Handler()).Methods(http.MethodGet).Name("metrics")
+}
@@ -0,0 +1,176 @@
+// Package archive moves a forum between databases as JSON Lines: a header,
+// then the participating users, the forum, its threads, posts and votes, one
+// record per line and in that order.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"technopark-dbms-forum/models"
+)
+
+// Version is bumped whenever a record changes incompatibly.
+const Version = 1
+
+const (
+	KindHeader = "header"
+	KindUser   = "user"
+	KindForum  = "forum"
+	KindThread = "thread"
+	KindPost   = "post"
+	KindVote   = "vote"
+)
+
+// rank orders the kinds: a record may not follow one of a higher rank, so an
+// importer meets every reference before the record using it.
+var rank = map[string]int{KindHeader: 0, KindUser: 1, KindForum: 2, KindThread: 3, KindPost: 4, KindVote: 5}
+
+type Header struct {
+	Version  int       `json:"version"`
+	Forum    string    `json:"forum"`
+	Exported time.Time `json:"exported"`
+}
+
+// User leaves out the password hash: imported users log in after a reset.
+type User struct {
+	Nickname string  `json:"nickname"`
+	FullName string  `json:"fullname"`
+	About    *string `json:"about"`
+	Email    *string `json:"email"`
+}
+
+type Forum struct {
+	Slug  string `json:"slug"`
+	User  string `json:"user"`
+	Title string `json:"title"`
+}
+
+// Thread, Post and Vote carry the ids of the source database; an import
+// assigns new ones.
+type Thread struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	Message   string    `json:"message"`
+	Slug      *string   `json:"slug"`
+	Created   time.Time `json:"created"`
+	IsDeleted bool      `json:"isDeleted"`
+}
+
+// Path is informational: the import rebuilds it from Parent.
+type Post struct {
+	ID        int64     `json:"id"`
+	Parent    *int64    `json:"parent"`
+	Thread    int       `json:"thread"`
+	Author    string    `json:"author"`
+	Message   string    `json:"message"`
+	Created   time.Time `json:"created"`
+	IsEdited  bool      `json:"isEdited"`
+	IsDeleted bool      `json:"isDeleted"`
+	Path      []int64   `json:"path"`
+}
+
+type Vote struct {
+	Thread int    `json:"thread"`
+	Author string `json:"author"`
+	Voice  int    `json:"voice"`
+}
+
+// Summary counts what an import created. Users only counts the users that
+// were missing in the target database.
+type Summary struct {
+	Forum   string `json:"forum"`
+	Users   int    `json:"users"`
+	Threads int    `json:"threads"`
+	Posts   int    `json:"posts"`
+	Votes   int    `json:"votes"`
+}
+
+type Store interface {
+	// Export writes the forum from a single snapshot, streaming as it reads.
+	Export(ctx context.Context, slug string, w *Writer) error
+	// Import creates the forum of r in one transaction, under slug if it is
+	// not empty.
+	Import(ctx context.Context, r *Reader, slug string) (Summary, error)
+}
+
+type record struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Writer struct {
+	enc *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &Writer{enc: enc}
+}
+
+func (w *Writer) Write(kind string, data interface{}) error {
+	return w.enc.Encode(struct {
+		Type string      `json:"type"`
+		Data interface{} `json:"data"`
+	}{Type: kind, Data: data})
+}
+
+// Reader decodes records one at a time and checks their order, so a store
+// can import an archive of any size.
+type Reader struct {
+	dec   *json.Decoder
+	count int
+	last  string
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(r)}
+}
+
+// Next returns the kind of the next record and its undecoded data, or io.EOF
+// after the last one. Malformed input is reported as a bad request naming
+// the record.
+func (r *Reader) Next() (string, json.RawMessage, error) {
+	var rec record
+	r.count++
+	if err := r.dec.Decode(&rec); err != nil {
+		if err == io.EOF {
+			r.count--
+			if r.count == 0 {
+				return "", nil, models.ErrBadRequest.WithMessage("Archive is empty")
+			}
+			return "", nil, io.EOF
+		}
+		return "", nil, r.Errorf("%v", err)
+	}
+
+	current, ok := rank[rec.Type]
+	switch {
+	case !ok:
+		return "", nil, r.Errorf("unknown type %q", rec.Type)
+	case r.count == 1 && rec.Type != KindHeader:
+		return "", nil, r.Errorf("archive must start with a header")
+	case r.count > 1 && (rec.Type == KindHeader || current < rank[r.last]):
+		return "", nil, r.Errorf("%s can't follow %s", rec.Type, r.last)
+	}
+	r.last = rec.Type
+	return rec.Type, rec.Data, nil
+}
+
+// Decode unmarshals the data of the current record into v.
+func (r *Reader) Decode(data json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return r.Errorf("%v", err)
+	}
+	return nil
+}
+
+// Errorf makes a bad request naming the current record.
+func (r *Reader) Errorf(format string, args ...interface{}) error {
+	args = append([]interface{}{r.count}, args...)
+	return models.ErrBadRequest.WithMessage("Record %d: "+format, args...)
+}
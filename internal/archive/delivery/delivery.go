@@ -0,0 +1,68 @@
+package delivery
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/internal/archive"
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/internal/logging"
+)
+
+const contentType = "application/x-ndjson"
+
+type ArchiveHandler struct {
+	Store archive.Store
+	Log   zerolog.Logger
+}
+
+func NewArchiveHandler(r *mux.Router, store archive.Store, log zerolog.Logger) {
+	handler := &ArchiveHandler{Store: store, Log: log}
+
+	r.HandleFunc("/api/forum/import", handler.Import).Methods(http.MethodPost).Name("forum_import")
+	r.HandleFunc("/api/forum/{slug}/export", handler.Export).Methods(http.MethodGet).Name("forum_export")
+}
+
+// started tells whether the archive began streaming: from then on an error
+// can only cut the response short.
+type started struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (s *started) Write(data []byte) (int, error) {
+	s.written = true
+	return s.ResponseWriter.Write(data)
+}
+
+func (a *ArchiveHandler) Export(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": slug + ".jsonl"}))
+
+	out := &started{ResponseWriter: w}
+	err := a.Store.Export(r.Context(), slug, archive.NewWriter(out))
+	if err == nil {
+		return
+	}
+	if out.written {
+		logging.For(r.Context(), a.Log).Error().Err(err).Str("forum", slug).Msg("export interrupted")
+		return
+	}
+	w.Header().Del("Content-Disposition")
+	w.Header().Set("Content-Type", "application/json")
+	httpio.WriteError(w, err)
+}
+
+func (a *ArchiveHandler) Import(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	summary, err := a.Store.Import(r.Context(), archive.NewReader(r.Body), r.URL.Query().Get("slug"))
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+	httpio.WriteJSON(w, http.StatusCreated, summary)
+}
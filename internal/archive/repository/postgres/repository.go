@@ -0,0 +1,422 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"technopark-dbms-forum/internal/archive"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/models"
+)
+
+// batchSize bounds the rows of one INSERT and the post ids reserved at once.
+const batchSize = 500
+
+type postgresArchiveStore struct {
+	Conn database.Conn
+}
+
+func NewPostgresArchiveStore(Conn database.Conn) archive.Store {
+	return &postgresArchiveStore{Conn: Conn}
+}
+
+// Export reads from one repeatable read transaction, so posts written
+// meanwhile are either exported with their thread or not at all.
+func (p *postgresArchiveStore) Export(ctx context.Context, slug string, w *archive.Writer) error {
+	tx, err := p.Conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return database.Translate(err)
+	}
+	defer tx.Rollback(ctx)
+
+	var forum archive.Forum
+	err = tx.QueryRow(ctx, `SELECT slug, "user", title FROM forum WHERE slug = $1;`, slug).
+		Scan(&forum.Slug, &forum.User, &forum.Title)
+	if err != nil {
+		return database.NotFound(err, models.ForumNotFound(slug))
+	}
+	if err := w.Write(archive.KindHeader, archive.Header{Version: archive.Version, Forum: forum.Slug, Exported: time.Now()}); err != nil {
+		return err
+	}
+
+	err = exportRows(ctx, tx, w, archive.KindUser, `SELECT nickname, fullname, about, email FROM users WHERE nickname IN (
+			SELECT "user" FROM forum WHERE slug = $1
+			UNION SELECT author FROM thread WHERE forum = $1
+			UNION SELECT p.author FROM post p JOIN thread t ON t.id = p.thread WHERE t.forum = $1
+			UNION SELECT v.author FROM votes v JOIN thread t ON t.id = v.thread WHERE t.forum = $1)
+		ORDER BY nickname;`, forum.Slug, func(rows pgx.Rows) (interface{}, error) {
+		var user archive.User
+		err := rows.Scan(&user.Nickname, &user.FullName, &user.About, &user.Email)
+		return user, err
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Write(archive.KindForum, forum); err != nil {
+		return err
+	}
+
+	err = exportRows(ctx, tx, w, archive.KindThread, `SELECT id, title, author, message, slug, created, isDeleted
+		FROM thread WHERE forum = $1 ORDER BY id;`, forum.Slug, func(rows pgx.Rows) (interface{}, error) {
+		var thread archive.Thread
+		err := rows.Scan(&thread.ID, &thread.Title, &thread.Author, &thread.Message, &thread.Slug, &thread.Created, &thread.IsDeleted)
+		return thread, err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Ordering by id puts every parent before its replies.
+	err = exportRows(ctx, tx, w, archive.KindPost, `SELECT p.id, NULLIF(p.parent, 0), p.thread, p.author, p.message, p.created,
+			p.isEdited, p.isDeleted, p.path
+		FROM post p JOIN thread t ON t.id = p.thread WHERE t.forum = $1 ORDER BY p.id;`, forum.Slug, func(rows pgx.Rows) (interface{}, error) {
+		var post archive.Post
+		err := rows.Scan(&post.ID, &post.Parent, &post.Thread, &post.Author, &post.Message, &post.Created,
+			&post.IsEdited, &post.IsDeleted, &post.Path)
+		return post, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return exportRows(ctx, tx, w, archive.KindVote, `SELECT v.thread, v.author, v.voice
+		FROM votes v JOIN thread t ON t.id = v.thread WHERE t.forum = $1 ORDER BY v.id;`, forum.Slug, func(rows pgx.Rows) (interface{}, error) {
+		var vote archive.Vote
+		err := rows.Scan(&vote.Thread, &vote.Author, &vote.Voice)
+		return vote, err
+	})
+}
+
+func exportRows(ctx context.Context, tx pgx.Tx, w *archive.Writer, kind string, query string, slug string,
+	scan func(pgx.Rows) (interface{}, error)) error {
+	rows, err := tx.Query(ctx, query, slug)
+	if err != nil {
+		return database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		value, err := scan(rows)
+		if err != nil {
+			return database.Translate(err)
+		}
+		if err := w.Write(kind, value); err != nil {
+			return err
+		}
+	}
+	return database.Translate(rows.Err())
+}
+
+// Import inserts rows through the usual triggers, which rebuild post paths,
+// users_forum and thread votes. Posts get ids reserved from the sequence in
+// archive order, so replies still sort after their parents.
+func (p *postgresArchiveStore) Import(ctx context.Context, r *archive.Reader, slug string) (archive.Summary, error) {
+	_, data, err := r.Next()
+	if err != nil {
+		return archive.Summary{}, err
+	}
+	var header archive.Header
+	if err := r.Decode(data, &header); err != nil {
+		return archive.Summary{}, err
+	}
+	if header.Version != archive.Version {
+		return archive.Summary{}, r.Errorf("unsupported version %d, expected %d", header.Version, archive.Version)
+	}
+	if slug == "" {
+		slug = header.Forum
+	}
+
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return archive.Summary{}, database.Translate(err)
+	}
+	defer tx.Rollback(ctx)
+
+	imp := &importer{
+		tx:      tx,
+		r:       r,
+		summary: archive.Summary{Forum: slug},
+		threads: make(map[int]int),
+		posts:   make(map[int64]int64),
+	}
+	for {
+		kind, data, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return archive.Summary{}, err
+		}
+		if err := imp.add(ctx, kind, data); err != nil {
+			return archive.Summary{}, err
+		}
+	}
+	if err := imp.finish(ctx); err != nil {
+		return archive.Summary{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return archive.Summary{}, database.Translate(err)
+	}
+	return imp.summary, nil
+}
+
+type importer struct {
+	tx      pgx.Tx
+	r       *archive.Reader
+	summary archive.Summary
+	created bool
+
+	// threads and posts map source ids to the new ones.
+	threads map[int]int
+	posts   map[int64]int64
+	// reserved holds post ids taken from the sequence but not used yet.
+	reserved []int64
+
+	postRows []interface{}
+	voteRows []interface{}
+}
+
+func (i *importer) add(ctx context.Context, kind string, data []byte) error {
+	if kind != archive.KindUser && kind != archive.KindForum && !i.created {
+		return i.r.Errorf("%s comes before the forum", kind)
+	}
+
+	switch kind {
+	case archive.KindUser:
+		var user archive.User
+		if err := i.r.Decode(data, &user); err != nil {
+			return err
+		}
+		return i.user(ctx, user)
+	case archive.KindForum:
+		var forum archive.Forum
+		if err := i.r.Decode(data, &forum); err != nil {
+			return err
+		}
+		return i.forum(ctx, forum)
+	case archive.KindThread:
+		var thread archive.Thread
+		if err := i.r.Decode(data, &thread); err != nil {
+			return err
+		}
+		return i.thread(ctx, thread)
+	case archive.KindPost:
+		var post archive.Post
+		if err := i.r.Decode(data, &post); err != nil {
+			return err
+		}
+		return i.post(ctx, post)
+	case archive.KindVote:
+		var vote archive.Vote
+		if err := i.r.Decode(data, &vote); err != nil {
+			return err
+		}
+		return i.vote(ctx, vote)
+	}
+	return i.r.Errorf("unexpected %s", kind)
+}
+
+// user keeps a user that already exists in the target database as it is.
+func (i *importer) user(ctx context.Context, user archive.User) error {
+	tag, err := i.tx.Exec(ctx, `INSERT INTO users(nickname, fullname, about, email) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;`, user.Nickname, user.FullName, user.About, user.Email)
+	if err != nil {
+		return database.Translate(err)
+	}
+	if tag.RowsAffected() == 1 {
+		i.summary.Users++
+		return nil
+	}
+
+	var exists bool
+	err = i.tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE nickname = $1);`, user.Nickname).Scan(&exists)
+	if err != nil {
+		return database.Translate(err)
+	}
+	if !exists {
+		return models.ErrConflict.WithMessage("Can't import user %s: the email belongs to another user", user.Nickname).
+			WithDetail("nickname", user.Nickname)
+	}
+	return nil
+}
+
+func (i *importer) forum(ctx context.Context, forum archive.Forum) error {
+	if i.created {
+		return i.r.Errorf("archive holds more than one forum")
+	}
+	_, err := i.tx.Exec(ctx, `INSERT INTO forum(slug, "user", title) VALUES ($1, $2, $3);`,
+		i.summary.Forum, forum.User, forum.Title)
+	if err != nil {
+		err = database.Translate(err)
+		if errors.Is(err, models.ErrConflict) {
+			return models.ErrConflict.WithMessage("Forum %s already exists", i.summary.Forum).
+				WithDetail("slug", i.summary.Forum)
+		}
+		return err
+	}
+	i.created = true
+	return nil
+}
+
+func (i *importer) thread(ctx context.Context, thread archive.Thread) error {
+	var id int
+	err := i.tx.QueryRow(ctx, `INSERT INTO thread(title, author, created, forum, message, slug, isDeleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;`,
+		thread.Title, thread.Author, thread.Created, i.summary.Forum, thread.Message, thread.Slug, thread.IsDeleted).
+		Scan(&id)
+	if err != nil {
+		err = database.Translate(err)
+		if errors.Is(err, models.ErrConflict) && thread.Slug != nil {
+			return models.ErrConflict.WithMessage("Can't import thread %d: slug %s is taken", thread.ID, *thread.Slug).
+				WithDetail("slug", *thread.Slug)
+		}
+		return err
+	}
+	i.threads[thread.ID] = id
+	i.summary.Threads++
+	return nil
+}
+
+func (i *importer) post(ctx context.Context, post archive.Post) error {
+	thread, ok := i.threads[post.Thread]
+	if !ok {
+		return i.r.Errorf("post %d belongs to unknown thread %d", post.ID, post.Thread)
+	}
+	var parent *int64
+	if post.Parent != nil && *post.Parent != 0 {
+		id, ok := i.posts[*post.Parent]
+		if !ok {
+			return i.r.Errorf("post %d replies to post %d that does not precede it", post.ID, *post.Parent)
+		}
+		parent = &id
+	}
+	if _, ok := i.posts[post.ID]; ok {
+		return i.r.Errorf("post %d is repeated", post.ID)
+	}
+
+	if len(i.reserved) == 0 {
+		if err := i.reserve(ctx); err != nil {
+			return err
+		}
+	}
+	id := i.reserved[0]
+	i.reserved = i.reserved[1:]
+	i.posts[post.ID] = id
+
+	i.postRows = append(i.postRows, id, post.Author, post.Created, i.summary.Forum, post.Message,
+		parent, thread, post.IsEdited, post.IsDeleted)
+	if len(i.postRows) >= batchSize*postColumns {
+		return i.flushPosts(ctx)
+	}
+	return nil
+}
+
+func (i *importer) reserve(ctx context.Context) error {
+	rows, err := i.tx.Query(ctx, `SELECT nextval(pg_get_serial_sequence('post', 'id'))
+		FROM generate_series(1, $1);`, batchSize)
+	if err != nil {
+		return database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return database.Translate(err)
+		}
+		i.reserved = append(i.reserved, id)
+	}
+	if err := rows.Err(); err != nil {
+		return database.Translate(err)
+	}
+	sort.Slice(i.reserved, func(a, b int) bool { return i.reserved[a] < i.reserved[b] })
+	return nil
+}
+
+func (i *importer) vote(ctx context.Context, vote archive.Vote) error {
+	if err := i.flushPosts(ctx); err != nil {
+		return err
+	}
+	thread, ok := i.threads[vote.Thread]
+	if !ok {
+		return i.r.Errorf("vote of %s is for unknown thread %d", vote.Author, vote.Thread)
+	}
+	i.voteRows = append(i.voteRows, vote.Author, vote.Voice, thread)
+	if len(i.voteRows) >= batchSize*voteColumns {
+		return i.flushVotes(ctx)
+	}
+	return nil
+}
+
+const (
+	postColumns = 9
+	voteColumns = 3
+)
+
+func (i *importer) flushPosts(ctx context.Context) error {
+	count, err := insertRows(ctx, i.tx, `INSERT INTO post(id, author, created, forum, message, parent, thread, isEdited, isDeleted) VALUES`,
+		postColumns, i.postRows)
+	i.summary.Posts += count
+	i.postRows = i.postRows[:0]
+	return err
+}
+
+func (i *importer) flushVotes(ctx context.Context) error {
+	count, err := insertRows(ctx, i.tx, `INSERT INTO votes(author, voice, thread) VALUES`, voteColumns, i.voteRows)
+	i.summary.Votes += count
+	i.voteRows = i.voteRows[:0]
+	return err
+}
+
+func insertRows(ctx context.Context, tx pgx.Tx, query string, columns int, values []interface{}) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+	var placeholders []string
+	for row := 0; row < len(values)/columns; row++ {
+		params := make([]string, columns)
+		for column := range params {
+			params[column] = fmt.Sprintf("$%d", row*columns+column+1)
+		}
+		placeholders = append(placeholders, "("+strings.Join(params, ", ")+")")
+	}
+
+	tag, err := tx.Exec(ctx, query+" "+strings.Join(placeholders, ", ")+";", values...)
+	if err != nil {
+		return 0, database.Translate(err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// finish rebuilds the forum counters: the insert triggers count deleted
+// threads and posts, the counters must not. Notifications about replies and
+// mentions are dropped, they would replay the forum's whole history.
+func (i *importer) finish(ctx context.Context) error {
+	if !i.created {
+		return models.ErrBadRequest.WithMessage("Archive holds no forum")
+	}
+	if err := i.flushPosts(ctx); err != nil {
+		return err
+	}
+	if err := i.flushVotes(ctx); err != nil {
+		return err
+	}
+
+	_, err := i.tx.Exec(ctx, `UPDATE forum SET
+			threads = (SELECT count(*) FROM thread WHERE forum = $1 AND NOT isDeleted),
+			posts = (SELECT count(*) FROM post p JOIN thread t ON t.id = p.thread
+				WHERE t.forum = $1 AND NOT p.isDeleted AND NOT t.isDeleted)
+		WHERE slug = $1;`, i.summary.Forum)
+	if err != nil {
+		return database.Translate(err)
+	}
+	_, err = i.tx.Exec(ctx, `DELETE FROM notifications
+		WHERE post IN (SELECT p.id FROM post p JOIN thread t ON t.id = p.thread WHERE t.forum = $1);`, i.summary.Forum)
+	return database.Translate(err)
+}
@@ -0,0 +1,180 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"technopark-dbms-forum/internal/archive"
+	archiveRepo "technopark-dbms-forum/internal/archive/repository/postgres"
+	"technopark-dbms-forum/internal/migrations"
+	"technopark-dbms-forum/models"
+)
+
+// testDatabase connects to FORUM_TEST_DATABASE_URL and migrates it. The
+// test writes rows of its own there and removes them afterwards.
+func testDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("FORUM_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("FORUM_TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := migrations.New(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func exec(t *testing.T, pool *pgxpool.Pool, sql string, args ...interface{}) {
+	t.Helper()
+	if _, err := pool.Exec(context.Background(), sql, args...); err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+}
+
+func scan(t *testing.T, pool *pgxpool.Pool, sql string, args []interface{}, dest ...interface{}) {
+	t.Helper()
+	if err := pool.QueryRow(context.Background(), sql, args...).Scan(dest...); err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+}
+
+func dropForum(t *testing.T, pool *pgxpool.Pool, slug string) {
+	t.Helper()
+	exec(t, pool, `DELETE FROM votes WHERE thread IN (SELECT id FROM thread WHERE forum = $1);`, slug)
+	exec(t, pool, `DELETE FROM post WHERE thread IN (SELECT id FROM thread WHERE forum = $1);`, slug)
+	exec(t, pool, `DELETE FROM thread WHERE forum = $1;`, slug)
+	exec(t, pool, `DELETE FROM users_forum WHERE slug = $1;`, slug)
+	exec(t, pool, `DELETE FROM forum WHERE slug = $1;`, slug)
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	pool := testDatabase(t)
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	anne, jack := "anne_"+suffix, "jack_"+suffix
+	source, target, again := "src_"+suffix, "dst_"+suffix, "again_"+suffix
+	live, deleted := "live-"+suffix, "deleted-"+suffix
+
+	t.Cleanup(func() {
+		for _, slug := range []string{source, target, again} {
+			dropForum(t, pool, slug)
+		}
+		exec(t, pool, `DELETE FROM users WHERE nickname IN ($1, $2);`, anne, jack)
+	})
+
+	exec(t, pool, `INSERT INTO users(nickname, fullname, email) VALUES ($1, 'Anne', $1 || '@sea.org'), ($2, 'Jack', $2 || '@sea.org');`, anne, jack)
+	exec(t, pool, `INSERT INTO forum(slug, "user", title) VALUES ($1, $2, 'Pirates');`, source, anne)
+	var liveID, deletedID int
+	scan(t, pool, `INSERT INTO thread(title, author, forum, message, slug) VALUES ('Live', $1, $2, 'Here', $3) RETURNING id;`,
+		[]interface{}{anne, source, live}, &liveID)
+	scan(t, pool, `INSERT INTO thread(title, author, forum, message, slug) VALUES ('Gone', $1, $2, 'There', $3) RETURNING id;`,
+		[]interface{}{jack, source, deleted}, &deletedID)
+
+	var root, reply, deepReply, removed int64
+	scan(t, pool, `INSERT INTO post(author, forum, message, thread) VALUES ($1, $2, 'root', $3) RETURNING id;`,
+		[]interface{}{anne, source, liveID}, &root)
+	scan(t, pool, `INSERT INTO post(author, forum, message, parent, thread) VALUES ($1, $2, 'reply', $3, $4) RETURNING id;`,
+		[]interface{}{jack, source, root, liveID}, &reply)
+	scan(t, pool, `INSERT INTO post(author, forum, message, parent, thread) VALUES ($1, $2, 'deep reply', $3, $4) RETURNING id;`,
+		[]interface{}{anne, source, reply, liveID}, &deepReply)
+	scan(t, pool, `INSERT INTO post(author, forum, message, thread) VALUES ($1, $2, 'removed', $3) RETURNING id;`,
+		[]interface{}{jack, source, liveID}, &removed)
+	exec(t, pool, `INSERT INTO post(author, forum, message, thread) VALUES ($1, $2, 'in a deleted thread', $3);`, anne, source, deletedID)
+	exec(t, pool, `INSERT INTO votes(author, voice, thread) VALUES ($1, 1, $3), ($2, 1, $3);`, anne, jack, liveID)
+	exec(t, pool, `UPDATE post SET isDeleted = TRUE WHERE id = $1;`, removed)
+	exec(t, pool, `UPDATE thread SET isDeleted = TRUE WHERE id = $1;`, deletedID)
+
+	store := archiveRepo.NewPostgresArchiveStore(pool)
+	var exported bytes.Buffer
+	if err := store.Export(ctx, source, archive.NewWriter(&exported)); err != nil {
+		t.Fatal(err)
+	}
+	// Thread slugs are unique across forums, the source has to go first.
+	dropForum(t, pool, source)
+
+	summary, err := store.Import(ctx, archive.NewReader(bytes.NewReader(exported.Bytes())), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := archive.Summary{Forum: target, Threads: 2, Posts: 5, Votes: 2}
+	if summary != want {
+		t.Errorf("summary %+v, want %+v", summary, want)
+	}
+
+	var threads, posts int
+	scan(t, pool, `SELECT threads, posts FROM forum WHERE slug = $1;`, []interface{}{target}, &threads, &posts)
+	if threads != 1 || posts != 3 {
+		t.Errorf("forum counts %d threads and %d posts, want the live 1 and 3", threads, posts)
+	}
+
+	var newLiveID, votes int
+	scan(t, pool, `SELECT id, votes FROM thread WHERE slug = $1;`, []interface{}{live}, &newLiveID, &votes)
+	if newLiveID == liveID {
+		t.Errorf("thread kept its source id %d", liveID)
+	}
+	if votes != 2 {
+		t.Errorf("thread has %d votes, want 2", votes)
+	}
+
+	paths := make(map[string][]int64)
+	parents := make(map[string]int64)
+	ids := make(map[string]int64)
+	rows, err := pool.Query(ctx, `SELECT id, message, COALESCE(parent, 0), path FROM post WHERE thread = $1;`, newLiveID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id, parent int64
+		var message string
+		var path []int64
+		if err := rows.Scan(&id, &message, &parent, &path); err != nil {
+			t.Fatal(err)
+		}
+		ids[message], parents[message], paths[message] = id, parent, path
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for message, old := range map[string]int64{"root": root, "reply": reply, "deep reply": deepReply} {
+		if ids[message] == old {
+			t.Errorf("post %q kept its source id %d", message, old)
+		}
+	}
+	if parents["reply"] != ids["root"] || parents["deep reply"] != ids["reply"] {
+		t.Errorf("parents were not remapped: %v, ids %v", parents, ids)
+	}
+	wantPath := []int64{ids["root"], ids["reply"], ids["deep reply"]}
+	if got := paths["deep reply"]; len(got) != 3 || got[0] != wantPath[0] || got[1] != wantPath[1] || got[2] != wantPath[2] {
+		t.Errorf("deep reply has path %v, want %v", got, wantPath)
+	}
+
+	var participants int
+	scan(t, pool, `SELECT count(*) FROM users_forum WHERE slug = $1 AND nickname IN ($2, $3);`,
+		[]interface{}{target, anne, jack}, &participants)
+	if participants != 2 {
+		t.Errorf("users_forum lists %d of the 2 participants", participants)
+	}
+
+	_, err = store.Import(ctx, archive.NewReader(bytes.NewReader(exported.Bytes())), again)
+	var conflict *models.Error
+	if !errors.Is(err, models.ErrConflict) || !errors.As(err, &conflict) || conflict.Details["slug"] != live {
+		t.Errorf("importing the threads again gave %v, want a conflict on slug %s", err, live)
+	}
+}
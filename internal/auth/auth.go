@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+
+	"technopark-dbms-forum/models"
+)
+
+type AuthUseCase interface {
+	Login(ctx context.Context, credentials models.Credentials) (models.Session, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (string, error)
+	// Authorize checks that the caller may act as nickname.
+	Authorize(ctx context.Context, nickname string) error
+	// AuthorizeEdit checks that the caller may edit what author wrote in forum.
+	AuthorizeEdit(ctx context.Context, forum string, author string) error
+	// AuthorizeModerate checks that the caller moderates, owns or administers
+	// forum.
+	AuthorizeModerate(ctx context.Context, forum string) error
+	Moderators(ctx context.Context, forum string) ([]models.Moderator, error)
+	Appoint(ctx context.Context, forum string, nickname string) (models.Moderator, error)
+	Dismiss(ctx context.Context, forum string, nickname string) error
+}
+
+type AuthRepository interface {
+	SelectPasswordHash(ctx context.Context, nickname string) (string, string, error)
+	InsertSession(ctx context.Context, tokenHash []byte, session models.Session) error
+	SelectSession(ctx context.Context, tokenHash []byte) (string, error)
+	DeleteSession(ctx context.Context, tokenHash []byte) error
+	SelectRole(ctx context.Context, forum string, nickname string) (models.Role, error)
+	SelectModerators(ctx context.Context, forum string) ([]models.Moderator, error)
+	InsertModerator(ctx context.Context, moderator models.Moderator) (models.Moderator, error)
+	DeleteModerator(ctx context.Context, forum string, nickname string) error
+}
+
+type nicknameKey struct{}
+
+func WithNickname(ctx context.Context, nickname string) context.Context {
+	return context.WithValue(ctx, nicknameKey{}, nickname)
+}
+
+// Nickname returns the authenticated user of the request, if any.
+func Nickname(ctx context.Context) (string, bool) {
+	nickname, ok := ctx.Value(nicknameKey{}).(string)
+	return nickname, ok
+}
@@ -0,0 +1,133 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	domain "technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/models"
+)
+
+type AuthHandler struct {
+	AuthUseCase domain.AuthUseCase
+}
+
+func NewAuthHandler(r *mux.Router, authUseCase domain.AuthUseCase) {
+	handler := &AuthHandler{AuthUseCase: authUseCase}
+
+	r.HandleFunc("/api/auth/login", handler.Login).Methods(http.MethodPost).Name("auth_login")
+	r.HandleFunc("/api/auth/logout", handler.Logout).Methods(http.MethodPost).Name("auth_logout")
+
+	r.HandleFunc("/api/forum/{slug}/moderators", handler.Moderators).Methods(http.MethodGet).Name("forum_moderators")
+	r.HandleFunc("/api/forum/{slug}/moderators/{nickname}", handler.Appoint).Methods(http.MethodPut).Name("forum_moderator_appoint")
+	r.HandleFunc("/api/forum/{slug}/moderators/{nickname}", handler.Dismiss).Methods(http.MethodDelete).Name("forum_moderator_dismiss")
+}
+
+func (a *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var credentials models.Credentials
+	err := httpio.DecodeJSON(r, &credentials)
+	if err == nil {
+		err = httpio.Validate(credentials)
+	}
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	session, err := a.AuthUseCase.Login(r.Context(), credentials)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteJSON(w, http.StatusOK, session)
+}
+
+func (a *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	token := bearerToken(r)
+	if token == "" {
+		httpio.WriteError(w, models.ErrUnauthorized.WithMessage("Bearer token is required"))
+		return
+	}
+	if err := a.AuthUseCase.Logout(r.Context(), token); err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AuthHandler) Moderators(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	moderators, err := a.AuthUseCase.Moderators(r.Context(), mux.Vars(r)["slug"])
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteList(w, len(moderators), moderators)
+}
+
+func (a *AuthHandler) Appoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	moderator, err := a.AuthUseCase.Appoint(r.Context(), vars["slug"], vars["nickname"])
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteJSON(w, http.StatusOK, moderator)
+}
+
+func (a *AuthHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	if err := a.AuthUseCase.Dismiss(r.Context(), vars["slug"], vars["nickname"]); err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Middleware resolves the bearer token into the request's nickname. Requests
+// without a token pass through anonymously; a bad token is rejected rather
+// than silently downgraded.
+func Middleware(authUseCase domain.AuthUseCase) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			nickname, err := authUseCase.Authenticate(r.Context(), token)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				httpio.WriteError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(domain.WithNickname(r.Context(), nickname)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
@@ -0,0 +1,75 @@
+package instrumented
+
+import (
+	"context"
+
+	domain "technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/metrics"
+	"technopark-dbms-forum/models"
+)
+
+// authRepository times every call of the wrapped repository.
+type authRepository struct {
+	next    domain.AuthRepository
+	metrics *metrics.Metrics
+}
+
+func NewAuthRepository(next domain.AuthRepository, m *metrics.Metrics) domain.AuthRepository {
+	return &authRepository{next: next, metrics: m}
+}
+
+func (r *authRepository) SelectPasswordHash(ctx context.Context, nickname string) (string, string, error) {
+	done := r.metrics.Query("auth", "SelectPasswordHash")
+	stored, hash, err := r.next.SelectPasswordHash(ctx, nickname)
+	done(err)
+	return stored, hash, err
+}
+
+func (r *authRepository) InsertSession(ctx context.Context, tokenHash []byte, session models.Session) error {
+	done := r.metrics.Query("auth", "InsertSession")
+	err := r.next.InsertSession(ctx, tokenHash, session)
+	done(err)
+	return err
+}
+
+func (r *authRepository) SelectSession(ctx context.Context, tokenHash []byte) (string, error) {
+	done := r.metrics.Query("auth", "SelectSession")
+	result, err := r.next.SelectSession(ctx, tokenHash)
+	done(err)
+	return result, err
+}
+
+func (r *authRepository) DeleteSession(ctx context.Context, tokenHash []byte) error {
+	done := r.metrics.Query("auth", "DeleteSession")
+	err := r.next.DeleteSession(ctx, tokenHash)
+	done(err)
+	return err
+}
+
+func (r *authRepository) SelectRole(ctx context.Context, forum string, nickname string) (models.Role, error) {
+	done := r.metrics.Query("auth", "SelectRole")
+	result, err := r.next.SelectRole(ctx, forum, nickname)
+	done(err)
+	return result, err
+}
+
+func (r *authRepository) SelectModerators(ctx context.Context, forum string) ([]models.Moderator, error) {
+	done := r.metrics.Query("auth", "SelectModerators")
+	result, err := r.next.SelectModerators(ctx, forum)
+	done(err)
+	return result, err
+}
+
+func (r *authRepository) InsertModerator(ctx context.Context, moderator models.Moderator) (models.Moderator, error) {
+	done := r.metrics.Query("auth", "InsertModerator")
+	result, err := r.next.InsertModerator(ctx, moderator)
+	done(err)
+	return result, err
+}
+
+func (r *authRepository) DeleteModerator(ctx context.Context, forum string, nickname string) error {
+	done := r.metrics.Query("auth", "DeleteModerator")
+	err := r.next.DeleteModerator(ctx, forum, nickname)
+	done(err)
+	return err
+}
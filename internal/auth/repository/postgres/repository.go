@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	domain "technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/models"
+)
+
+type postgresAuthRepository struct {
+	Conn database.Conn
+}
+
+func NewPostgresAuthRepository(Conn database.Conn) domain.AuthRepository {
+	return &postgresAuthRepository{Conn: Conn}
+}
+
+// SelectPasswordHash returns the nickname as stored and its password hash,
+// which is empty for users registered without a password.
+func (p *postgresAuthRepository) SelectPasswordHash(ctx context.Context, nickname string) (string, string, error) {
+	var stored, hash string
+	err := p.Conn.QueryRow(ctx, `SELECT nickname, COALESCE(password_hash, '') FROM users WHERE nickname=$1;`,
+		nickname).Scan(&stored, &hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", models.UserNotFound(nickname)
+	}
+	return stored, hash, err
+}
+
+func (p *postgresAuthRepository) InsertSession(ctx context.Context, tokenHash []byte, session models.Session) error {
+	_, err := p.Conn.Exec(ctx, `INSERT INTO sessions(token_hash, nickname, expires) VALUES ($1, $2, $3);`,
+		tokenHash, session.Nickname, session.Expires)
+	return err
+}
+
+func (p *postgresAuthRepository) SelectSession(ctx context.Context, tokenHash []byte) (string, error) {
+	var nickname string
+	err := p.Conn.QueryRow(ctx, `SELECT nickname FROM sessions WHERE token_hash=$1 AND expires > now();`,
+		tokenHash).Scan(&nickname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", models.ErrUnauthorized.WithMessage("Session is expired or unknown")
+	}
+	return nickname, err
+}
+
+// DeleteSession also drops every expired session, there is no other reaper.
+func (p *postgresAuthRepository) DeleteSession(ctx context.Context, tokenHash []byte) error {
+	_, err := p.Conn.Exec(ctx, `DELETE FROM sessions WHERE token_hash=$1 OR expires <= now();`, tokenHash)
+	return err
+}
+
+// SelectRole reports whether nickname owns or moderates forum. Site admins
+// are not stored and never come back from here.
+func (p *postgresAuthRepository) SelectRole(ctx context.Context, forum string, nickname string) (models.Role, error) {
+	var role string
+	err := p.Conn.QueryRow(ctx, `SELECT CASE WHEN f."user" = $2 THEN 'owner'
+			WHEN m.nickname IS NOT NULL THEN 'moderator' ELSE '' END
+		FROM forum f LEFT JOIN forum_moderators m ON m.forum = f.slug AND m.nickname = $2
+		WHERE f.slug = $1;`, forum, nickname).Scan(&role)
+	if err != nil {
+		return models.RoleNone, database.NotFound(err, models.ForumNotFound(forum))
+	}
+	return models.Role(role), nil
+}
+
+func (p *postgresAuthRepository) SelectModerators(ctx context.Context, forum string) ([]models.Moderator, error) {
+	var slug string
+	err := p.Conn.QueryRow(ctx, `SELECT slug FROM forum WHERE slug=$1;`, forum).Scan(&slug)
+	if err != nil {
+		return nil, database.NotFound(err, models.ForumNotFound(forum))
+	}
+
+	rows, err := p.Conn.Query(ctx, `SELECT forum, nickname, appointed_by, created FROM forum_moderators
+		WHERE forum=$1 ORDER BY nickname;`, slug)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	var moderators []models.Moderator
+	for rows.Next() {
+		var moderator models.Moderator
+		err := rows.Scan(&moderator.Forum, &moderator.Nickname, &moderator.AppointedBy, &moderator.Created)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+		moderators = append(moderators, moderator)
+	}
+	return moderators, database.Translate(rows.Err())
+}
+
+// InsertModerator is idempotent: appointing a moderator again returns the
+// original appointment.
+func (p *postgresAuthRepository) InsertModerator(ctx context.Context, moderator models.Moderator) (models.Moderator, error) {
+	var result models.Moderator
+	err := p.Conn.QueryRow(ctx, `INSERT INTO forum_moderators(forum, nickname, appointed_by)
+		SELECT f.slug, u.nickname, $3 FROM forum f, users u WHERE f.slug=$1 AND u.nickname=$2
+		ON CONFLICT (forum, nickname) DO UPDATE SET forum=EXCLUDED.forum
+		RETURNING forum, nickname, appointed_by, created;`,
+		moderator.Forum, moderator.Nickname, moderator.AppointedBy).
+		Scan(&result.Forum, &result.Nickname, &result.AppointedBy, &result.Created)
+	if err != nil {
+		return models.Moderator{}, database.NotFound(err, models.UserNotFound(moderator.Nickname))
+	}
+	return result, nil
+}
+
+func (p *postgresAuthRepository) DeleteModerator(ctx context.Context, forum string, nickname string) error {
+	tag, err := p.Conn.Exec(ctx, `DELETE FROM forum_moderators WHERE forum=$1 AND nickname=$2;`, forum, nickname)
+	if err != nil {
+		return database.Translate(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound.WithMessage("%s is not a moderator of %s", nickname, forum).
+			WithDetail("nickname", nickname)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"technopark-dbms-forum/configs"
+	domain "technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/models"
+)
+
+var errBadCredentials = models.ErrUnauthorized.WithMessage("Wrong nickname or password")
+
+type AuthUsecase struct {
+	authRepo domain.AuthRepository
+	cfg      configs.AuthConfig
+}
+
+func NewAuthUsecase(authRepo domain.AuthRepository, cfg configs.AuthConfig) domain.AuthUseCase {
+	return &AuthUsecase{authRepo: authRepo, cfg: cfg}
+}
+
+func (a *AuthUsecase) Login(ctx context.Context, credentials models.Credentials) (models.Session, error) {
+	nickname, hash, err := a.authRepo.SelectPasswordHash(ctx, credentials.Nickname)
+	if errors.Is(err, models.ErrNotFound) {
+		return models.Session{}, errBadCredentials
+	}
+	if err != nil {
+		return models.Session{}, err
+	}
+	if hash == "" || !domain.CheckPassword(hash, credentials.Password) {
+		return models.Session{}, errBadCredentials
+	}
+
+	token, tokenHash, err := domain.NewToken()
+	if err != nil {
+		return models.Session{}, err
+	}
+	session := models.Session{
+		Token:    token,
+		Nickname: nickname,
+		Expires:  time.Now().Add(a.cfg.SessionTTL),
+	}
+	if err := a.authRepo.InsertSession(ctx, tokenHash, session); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+func (a *AuthUsecase) Logout(ctx context.Context, token string) error {
+	return a.authRepo.DeleteSession(ctx, domain.TokenHash(token))
+}
+
+func (a *AuthUsecase) Authenticate(ctx context.Context, token string) (string, error) {
+	return a.authRepo.SelectSession(ctx, domain.TokenHash(token))
+}
+
+// Authorize lets anonymous requests through unless auth.enforce is set, so
+// clients that predate sessions keep working. Users who set a password can
+// only be acted as with their session either way.
+func (a *AuthUsecase) Authorize(ctx context.Context, nickname string) error {
+	identity, ok := domain.Nickname(ctx)
+	if !ok {
+		protected, err := a.protected(ctx, nickname)
+		if err != nil {
+			return err
+		}
+		if protected {
+			return models.ErrUnauthorized.WithMessage("Log in to act as %s", nickname)
+		}
+		return nil
+	}
+	if !strings.EqualFold(identity, nickname) {
+		return models.ErrForbidden.WithMessage("Logged in as %s, cannot act as %s", identity, nickname).
+			WithDetail("nickname", nickname)
+	}
+	return nil
+}
+
+// AuthorizeEdit follows Authorize for anonymous callers: they may only edit
+// content of authors without a password. A logged in caller may edit their
+// own content, or anything in a forum they moderate, own or administer.
+func (a *AuthUsecase) AuthorizeEdit(ctx context.Context, forum string, author string) error {
+	identity, ok := domain.Nickname(ctx)
+	if !ok {
+		protected, err := a.protected(ctx, author)
+		if err != nil {
+			return err
+		}
+		if protected {
+			return models.ErrUnauthorized.WithMessage("Log in as %s or a moderator of %s to edit this", author, forum)
+		}
+		return nil
+	}
+	if strings.EqualFold(identity, author) {
+		return nil
+	}
+
+	role, err := a.role(ctx, forum, identity)
+	if err != nil {
+		return err
+	}
+	if role == models.RoleNone {
+		return models.ErrForbidden.WithMessage("Only the author or a moderator of %s can edit this", forum)
+	}
+	return nil
+}
+
+// AuthorizeModerate requires a session whatever auth.enforce says: there is
+// no author to fall back to.
+func (a *AuthUsecase) AuthorizeModerate(ctx context.Context, forum string) error {
+	identity, ok := domain.Nickname(ctx)
+	if !ok {
+		return models.ErrUnauthorized.WithMessage("Log in to moderate %s", forum)
+	}
+	role, err := a.role(ctx, forum, identity)
+	if err != nil {
+		return err
+	}
+	if role == models.RoleNone {
+		return models.ErrForbidden.WithMessage("Only a moderator of %s can do this", forum)
+	}
+	return nil
+}
+
+func (a *AuthUsecase) Moderators(ctx context.Context, forum string) ([]models.Moderator, error) {
+	return a.authRepo.SelectModerators(ctx, forum)
+}
+
+func (a *AuthUsecase) Appoint(ctx context.Context, forum string, nickname string) (models.Moderator, error) {
+	identity, err := a.manage(ctx, forum)
+	if err != nil {
+		return models.Moderator{}, err
+	}
+	return a.authRepo.InsertModerator(ctx, models.Moderator{Forum: forum, Nickname: nickname, AppointedBy: identity})
+}
+
+func (a *AuthUsecase) Dismiss(ctx context.Context, forum string, nickname string) error {
+	if _, err := a.manage(ctx, forum); err != nil {
+		return err
+	}
+	return a.authRepo.DeleteModerator(ctx, forum, nickname)
+}
+
+// manage requires a logged in owner of forum or a site admin, whatever
+// auth.enforce says.
+func (a *AuthUsecase) manage(ctx context.Context, forum string) (string, error) {
+	identity, ok := domain.Nickname(ctx)
+	if !ok {
+		return "", models.ErrUnauthorized.WithMessage("Log in to manage moderators of %s", forum)
+	}
+	role, err := a.role(ctx, forum, identity)
+	if err != nil {
+		return "", err
+	}
+	if role != models.RoleOwner && role != models.RoleAdmin {
+		return "", models.ErrForbidden.WithMessage("Only the owner of %s can manage its moderators", forum)
+	}
+	return identity, nil
+}
+
+// protected tells whether acting as nickname takes a session: always with
+// auth.enforce, otherwise when nickname has a password. Unknown users are not
+// protected, the request fails on its own further on.
+func (a *AuthUsecase) protected(ctx context.Context, nickname string) (bool, error) {
+	if a.cfg.Enforce {
+		return true, nil
+	}
+	if nickname == "" {
+		return false, nil
+	}
+	_, hash, err := a.authRepo.SelectPasswordHash(ctx, nickname)
+	if errors.Is(err, models.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return hash != "", nil
+}
+
+// role also checks that forum exists, admins included.
+func (a *AuthUsecase) role(ctx context.Context, forum string, nickname string) (models.Role, error) {
+	role, err := a.authRepo.SelectRole(ctx, forum, nickname)
+	if err != nil {
+		return models.RoleNone, err
+	}
+	for _, admin := range a.cfg.Admins {
+		if strings.EqualFold(admin, nickname) {
+			return models.RoleAdmin, nil
+		}
+	}
+	return role, nil
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"technopark-dbms-forum/configs"
+	domain "technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/models"
+)
+
+// fakeRepository knows users by nickname with their password hashes and the
+// roles they hold in forum "pirates".
+type fakeRepository struct {
+	domain.AuthRepository
+	hashes map[string]string
+	roles  map[string]models.Role
+}
+
+func (f *fakeRepository) SelectPasswordHash(ctx context.Context, nickname string) (string, string, error) {
+	hash, ok := f.hashes[strings.ToLower(nickname)]
+	if !ok {
+		return "", "", models.UserNotFound(nickname)
+	}
+	return nickname, hash, nil
+}
+
+func (f *fakeRepository) SelectRole(ctx context.Context, forum string, nickname string) (models.Role, error) {
+	return f.roles[strings.ToLower(nickname)], nil
+}
+
+func newUsecase(enforce bool) domain.AuthUseCase {
+	repo := &fakeRepository{
+		hashes: map[string]string{"jack": "", "anne": "$2a$10$hash", "mod": "$2a$10$hash"},
+		roles:  map[string]models.Role{"mod": models.RoleModerator},
+	}
+	return NewAuthUsecase(repo, configs.AuthConfig{Enforce: enforce})
+}
+
+func as(nickname string) context.Context {
+	if nickname == "" {
+		return context.Background()
+	}
+	return domain.WithNickname(context.Background(), nickname)
+}
+
+func check(t *testing.T, err error, want *models.Error) {
+	t.Helper()
+	if want == nil {
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+		return
+	}
+	if !errors.Is(err, want) {
+		t.Fatalf("got %v, want %s", err, want.Code)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		enforce  bool
+		caller   string
+		nickname string
+		want     *models.Error
+	}{
+		{"anonymous acts as a user without password", false, "", "jack", nil},
+		{"anonymous acts as an unknown user", false, "", "nobody", nil},
+		{"anonymous acts as a user with password", false, "", "anne", models.ErrUnauthorized},
+		{"anonymous acts as a user with password, any case", false, "", "ANNE", models.ErrUnauthorized},
+		{"anonymous with enforce", true, "", "jack", models.ErrUnauthorized},
+		{"session of the user", false, "anne", "Anne", nil},
+		{"session of another user", false, "jack", "anne", models.ErrForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := newUsecase(test.enforce).Authorize(as(test.caller), test.nickname)
+			check(t, err, test.want)
+		})
+	}
+}
+
+func TestAuthorizeEdit(t *testing.T) {
+	tests := []struct {
+		name    string
+		enforce bool
+		caller  string
+		author  string
+		want    *models.Error
+	}{
+		{"anonymous edits content of a user without password", false, "", "jack", nil},
+		{"anonymous edits content of a user with password", false, "", "anne", models.ErrUnauthorized},
+		{"anonymous with enforce", true, "", "jack", models.ErrUnauthorized},
+		{"author", false, "anne", "anne", nil},
+		{"moderator", false, "mod", "anne", nil},
+		{"another user", false, "jack", "anne", models.ErrForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := newUsecase(test.enforce).AuthorizeEdit(as(test.caller), "pirates", test.author)
+			check(t, err, test.want)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"technopark-dbms-forum/models"
+)
+
+// Signer turns cursors into opaque tokens and back. Tokens are
+// base64(payload).base64(hmac) so clients can neither read nor forge the
+// sort key inside.
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner signs with secret, or with a random key when it is empty. Cursors
+// signed with a random key do not survive a restart and are not accepted by
+// other instances.
+func NewSigner(secret string) (*Signer, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &Signer{secret: key}, nil
+}
+
+func (s *Signer) Encode(c models.Cursor) string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// Decode verifies token and checks it was issued for scope.
+func (s *Signer) Decode(token, scope string) (models.Cursor, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return models.Cursor{}, models.ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return models.Cursor{}, models.ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(mac, s.sign(payload)) {
+		return models.Cursor{}, models.ErrInvalidCursor
+	}
+
+	var c models.Cursor
+	if err := json.Unmarshal(payload, &c); err != nil || c.Scope != scope || len(c.Key) == 0 {
+		return models.Cursor{}, models.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func (s *Signer) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// acquiring takes connections from the pool itself to bound the wait for a
+// free one. Like *pgxpool.Pool it holds a connection until the statement,
+// the rows or the transaction are done with it.
+type acquiring struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// AcquireTimeout makes statements on pool give up after waiting timeout for
+// a connection. A zero timeout returns pool as it is.
+func AcquireTimeout(pool *pgxpool.Pool, timeout time.Duration) Conn {
+	if timeout <= 0 {
+		return pool
+	}
+	return &acquiring{pool: pool, timeout: timeout}
+}
+
+func (a *acquiring) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	acquireCtx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	conn, err := a.pool.Acquire(acquireCtx)
+	if err != nil && ctx.Err() == nil && acquireCtx.Err() != nil {
+		return nil, fmt.Errorf("no free connection within %v: %w", a.timeout, context.DeadlineExceeded)
+	}
+	return conn, err
+}
+
+func (a *acquiring) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	conn, err := a.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	return conn.Exec(ctx, sql, arguments...)
+}
+
+// Query returns rows carrying the error on failure, as *pgxpool.Pool does,
+// so that closing them before checking err stays safe.
+func (a *acquiring) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	conn, err := a.acquire(ctx)
+	if err != nil {
+		return errRows{err: err}, err
+	}
+	result, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		conn.Release()
+		return errRows{err: err}, err
+	}
+	return &acquiredRows{Rows: result, conn: conn}, nil
+}
+
+func (a *acquiring) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	conn, err := a.acquire(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return &acquiredRow{row: conn.QueryRow(ctx, sql, args...), conn: conn}
+}
+
+func (a *acquiring) Begin(ctx context.Context) (pgx.Tx, error) {
+	return a.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (a *acquiring) BeginTx(ctx context.Context, options pgx.TxOptions) (pgx.Tx, error) {
+	conn, err := a.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := conn.BeginTx(ctx, options)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return &acquiredTx{Tx: tx, conn: conn}, nil
+}
+
+type acquiredRows struct {
+	pgx.Rows
+	conn *pgxpool.Conn
+}
+
+func (r *acquiredRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+func (r *acquiredRows) Close() {
+	r.Rows.Close()
+	if r.conn != nil {
+		r.conn.Release()
+		r.conn = nil
+	}
+}
+
+type errRows struct {
+	err error
+}
+
+func (errRows) Close()                                         {}
+func (r errRows) Err() error                                   { return r.err }
+func (errRows) CommandTag() pgconn.CommandTag                  { return nil }
+func (errRows) FieldDescriptions() []pgproto3.FieldDescription { return nil }
+func (errRows) Next() bool                                     { return false }
+func (r errRows) Scan(dest ...interface{}) error               { return r.err }
+func (r errRows) Values() ([]interface{}, error)               { return nil, r.err }
+func (errRows) RawValues() [][]byte                            { return nil }
+
+type acquiredRow struct {
+	row  pgx.Row
+	conn *pgxpool.Conn
+}
+
+func (r *acquiredRow) Scan(dest ...interface{}) error {
+	defer r.conn.Release()
+	return r.row.Scan(dest...)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// acquiredTx gives the connection back once the transaction ends, be it by
+// Commit or by the deferred Rollback after it.
+type acquiredTx struct {
+	pgx.Tx
+	conn *pgxpool.Conn
+}
+
+func (t *acquiredTx) Commit(ctx context.Context) error {
+	err := t.Tx.Commit(ctx)
+	t.release()
+	return err
+}
+
+func (t *acquiredTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	t.release()
+	return err
+}
+
+func (t *acquiredTx) release() {
+	if t.conn != nil {
+		t.conn.Release()
+		t.conn = nil
+	}
+}
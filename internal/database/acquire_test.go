@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func TestAcquireTimeoutQueryErrorRows(t *testing.T) {
+	cfg, err := pgxpool.ParseConfig("host=127.0.0.1 port=1 user=nobody dbname=nothing connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.LazyConnect = true
+	pool, err := pgxpool.ConnectConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	rows, err := AcquireTimeout(pool, time.Second).Query(context.Background(), "SELECT 1")
+	if err == nil {
+		t.Fatal("expected the acquire to fail")
+	}
+	// Repositories may close the rows before looking at err.
+	rows.Close()
+	if rows.Next() || rows.Err() != err {
+		t.Fatalf("rows do not carry the error: %v", rows.Err())
+	}
+}
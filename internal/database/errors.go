@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"technopark-dbms-forum/models"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	// parentConflict is raised by the updatePath trigger when a post replies
+	// to a parent from another thread or to a missing one.
+	parentConflict = "00409"
+)
+
+var keyDetail = regexp.MustCompile(`Key \((.+)\)=\((.*)\)`)
+
+// Translate maps driver errors to domain errors. This is the only place that
+// knows about SQLSTATE codes.
+func Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return models.ErrNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.ErrTimeout
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case foreignKeyViolation:
+			return withKey(models.ErrNotFound, pgErr).WithMessage("Referenced item is not found: %s", pgErr.Detail)
+		case uniqueViolation:
+			return withKey(models.ErrConflict, pgErr)
+		case parentConflict:
+			return models.ErrConflict.WithMessage("Parent post was created in another thread").
+				WithDetail("constraint", "parent")
+		}
+	}
+	return err
+}
+
+// NotFound translates err, replacing a bare "no rows" with the more specific
+// not-found error of the caller.
+func NotFound(err error, specific *models.Error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return specific
+	}
+	return Translate(err)
+}
+
+func withKey(base *models.Error, pgErr *pgconn.PgError) *models.Error {
+	result := base
+	if pgErr.ConstraintName != "" {
+		result = result.WithDetail("constraint", pgErr.ConstraintName)
+	}
+	if match := keyDetail.FindStringSubmatch(pgErr.Detail); match != nil {
+		result = result.WithDetail(match[1], match[2])
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"technopark-dbms-forum/configs"
+)
+
+// NewPool opens a pgxpool on the extended protocol. Every statement goes
+// through a per-connection LRU cache of prepared statements, so hot queries
+// are parsed once per connection rather than on every call.
+func NewPool(ctx context.Context, cfg configs.PostgresConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.ConnString())
+	if err != nil {
+		return nil, fmt.Errorf("postgres config: %v", err)
+	}
+
+	poolConfig.MaxConns = int32(cfg.MaxConnections)
+	poolConfig.MinConns = int32(cfg.MinConnections)
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+
+	capacity := cfg.StatementCacheCapacity
+	if capacity == 0 {
+		poolConfig.ConnConfig.BuildStatementCache = nil
+	} else {
+		poolConfig.ConnConfig.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+			return stmtcache.New(conn, stmtcache.ModePrepare, capacity)
+		}
+	}
+
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("postgres connect: %v", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("postgres ping: %v", err)
+	}
+	return pool, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const pingTimeout = 2 * time.Second
+
+type PoolStats struct {
+	Healthy              bool   `json:"healthy"`
+	Error                string `json:"error,omitempty"`
+	MaxConns             int32  `json:"maxConns"`
+	TotalConns           int32  `json:"totalConns"`
+	AcquiredConns        int32  `json:"acquiredConns"`
+	IdleConns            int32  `json:"idleConns"`
+	ConstructingConns    int32  `json:"constructingConns"`
+	AcquireCount         int64  `json:"acquireCount"`
+	EmptyAcquireCount    int64  `json:"emptyAcquireCount"`
+	CanceledAcquireCount int64  `json:"canceledAcquireCount"`
+	AcquireDurationMs    int64  `json:"acquireDurationMs"`
+	NewConnsCount        int64  `json:"newConnsCount"`
+}
+
+func Stats(ctx context.Context, pool *pgxpool.Pool) PoolStats {
+	stat := pool.Stat()
+	stats := PoolStats{
+		Healthy:              true,
+		MaxConns:             stat.MaxConns(),
+		TotalConns:           stat.TotalConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		ConstructingConns:    stat.ConstructingConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDurationMs:    stat.AcquireDuration().Milliseconds(),
+		NewConnsCount:        stat.NewConnsCount(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := pool.Ping(ctx); err != nil {
+		stats.Healthy = false
+		stats.Error = err.Error()
+	}
+	return stats
+}
+
+// StatsHandler reports pool statistics, answering 503 when a connection
+// cannot be acquired and pinged.
+func StatsHandler(pool *pgxpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		stats := Stats(r.Context(), pool)
+
+		body, err := json.Marshal(stats)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if stats.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(body)
+	}
+}
@@ -0,0 +1,259 @@
+// Package events fans out forum changes to subscribers of this instance.
+// Changes are announced by database triggers on the forum_events channel, so
+// every instance sees the writes of every other one.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/configs"
+	"technopark-dbms-forum/models"
+)
+
+const (
+	channel = "forum_events"
+	// buffer is how far a subscriber may fall behind before it is dropped.
+	buffer = 64
+	// backlog is how many notifications may wait for their posts to load
+	// before new ones are dropped.
+	backlog = 1024
+)
+
+// Event types as sent to clients.
+const (
+	TypePost = "post"
+	TypeEdit = "edit"
+	TypeVote = "vote"
+)
+
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// Votes is the data of a vote event.
+type Votes struct {
+	Thread int `json:"thread"`
+	Votes  int `json:"votes"`
+}
+
+// notification is the payload the triggers send. Posts are sent by id only,
+// a NOTIFY payload cannot hold an arbitrary message: an edit by the id of
+// the post, an inserted batch by the range of its ids in the thread.
+type notification struct {
+	Type   string `json:"type"`
+	ID     int    `json:"id"`
+	First  int    `json:"first"`
+	Last   int    `json:"last"`
+	Thread int    `json:"thread"`
+	Forum  string `json:"forum"`
+	Votes  int    `json:"votes"`
+}
+
+// PostLoader fetches the posts of thread announced by a notification, ids
+// from first to last.
+type PostLoader func(ctx context.Context, thread int, first int, last int) ([]models.Post, error)
+
+type Hub struct {
+	cfg  configs.EventsConfig
+	load PostLoader
+	log  zerolog.Logger
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+	closed      bool
+}
+
+func NewHub(cfg configs.EventsConfig, load PostLoader, log zerolog.Logger) *Hub {
+	return &Hub{cfg: cfg, load: load, log: log, subscribers: make(map[string]map[chan Event]struct{})}
+}
+
+func (h *Hub) Config() configs.EventsConfig {
+	return h.cfg
+}
+
+func ThreadTopic(id int) string {
+	return "thread:" + strconv.Itoa(id)
+}
+
+// ForumTopic folds case as forum slugs are case-insensitive.
+func ForumTopic(slug string) string {
+	return "forum:" + strings.ToLower(slug)
+}
+
+// Subscribe returns the events of topic until cancel is called. The channel
+// is closed early when the subscriber falls behind.
+func (h *Hub) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[chan Event]struct{})
+	}
+	h.subscribers[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(topic, ch)
+	}
+}
+
+// Close ends every subscription so that streams finish and the server can
+// shut down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for topic, subscribers := range h.subscribers {
+		for ch := range subscribers {
+			h.remove(topic, ch)
+		}
+	}
+}
+
+// remove must be called with mu held.
+func (h *Hub) remove(topic string, ch chan Event) {
+	if _, ok := h.subscribers[topic][ch]; !ok {
+		return
+	}
+	delete(h.subscribers[topic], ch)
+	if len(h.subscribers[topic]) == 0 {
+		delete(h.subscribers, topic)
+	}
+	close(ch)
+}
+
+func (h *Hub) watched(topics ...string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		if len(h.subscribers[topic]) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hub) publish(event Event, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		for ch := range h.subscribers[topic] {
+			select {
+			case ch <- event:
+			default:
+				h.remove(topic, ch)
+			}
+		}
+	}
+}
+
+// Listen delivers notifications until ctx is done, holding one pool
+// connection. A lost connection is reacquired after a pause; notifications
+// sent meanwhile are lost. Posts are loaded by another goroutine so that a
+// slow query does not hold up receiving.
+func (h *Hub) Listen(ctx context.Context, pool *pgxpool.Pool) {
+	pending := make(chan notification, backlog)
+	go h.deliver(ctx, pending)
+
+	for {
+		err := h.listen(ctx, pool, pending)
+		if ctx.Err() != nil {
+			return
+		}
+		h.log.Error().Err(err).Msg("listening for events failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (h *Hub) listen(ctx context.Context, pool *pgxpool.Pool, pending chan<- notification) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection still has LISTEN active, so it must not go back to
+	// the pool.
+	defer func() {
+		conn.Conn().Close(context.Background())
+		conn.Release()
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		received, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var n notification
+		if err := json.Unmarshal([]byte(received.Payload), &n); err != nil {
+			h.log.Error().Err(err).Str("payload", received.Payload).Msg("bad notification")
+			continue
+		}
+		if !h.watched(n.topics()...) {
+			continue
+		}
+		select {
+		case pending <- n:
+		default:
+			h.log.Error().Str("payload", received.Payload).Msg("events backlog is full, dropping notification")
+		}
+	}
+}
+
+func (n notification) topics() []string {
+	return []string{ThreadTopic(n.Thread), ForumTopic(n.Forum)}
+}
+
+// deliver publishes pending notifications in the order they came in.
+func (h *Hub) deliver(ctx context.Context, pending <-chan notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-pending:
+			h.dispatch(ctx, n)
+		}
+	}
+}
+
+func (h *Hub) dispatch(ctx context.Context, n notification) {
+	topics := n.topics()
+	switch n.Type {
+	case TypePost, TypeEdit:
+		first, last := n.First, n.Last
+		if n.Type == TypeEdit {
+			first, last = n.ID, n.ID
+		}
+		posts, err := h.load(ctx, n.Thread, first, last)
+		if err != nil {
+			h.log.Error().Err(err).Int("first", first).Int("last", last).Msg("loading announced posts failed")
+			return
+		}
+		for _, post := range posts {
+			h.publish(Event{Type: n.Type, Data: post}, topics...)
+		}
+	case TypeVote:
+		h.publish(Event{Type: n.Type, Data: Votes{Thread: n.Thread, Votes: n.Votes}}, topics...)
+	}
+}
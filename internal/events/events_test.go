@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/configs"
+	"technopark-dbms-forum/models"
+)
+
+func TestDispatchBatch(t *testing.T) {
+	var loaded [][3]int
+	load := func(ctx context.Context, thread int, first int, last int) ([]models.Post, error) {
+		loaded = append(loaded, [3]int{thread, first, last})
+		var posts []models.Post
+		for id := first; id <= last; id += 2 {
+			posts = append(posts, models.Post{ID: id, Thread: thread})
+		}
+		return posts, nil
+	}
+	hub := NewHub(configs.EventsConfig{}, load, zerolog.Nop())
+	events, cancel := hub.Subscribe(ForumTopic("Pirates"))
+	defer cancel()
+
+	hub.dispatch(context.Background(), notification{Type: TypePost, Thread: 7, Forum: "pirates", First: 10, Last: 14})
+	hub.dispatch(context.Background(), notification{Type: TypeEdit, Thread: 7, Forum: "pirates", ID: 12})
+
+	if len(loaded) != 2 || loaded[0] != [3]int{7, 10, 14} || loaded[1] != [3]int{7, 12, 12} {
+		t.Fatalf("loaded %v", loaded)
+	}
+	want := []struct {
+		typ string
+		id  int
+	}{{TypePost, 10}, {TypePost, 12}, {TypePost, 14}, {TypeEdit, 12}}
+	for _, w := range want {
+		event := <-events
+		if post, ok := event.Data.(models.Post); !ok || event.Type != w.typ || post.ID != w.id {
+			t.Fatalf("got %s %+v, want %s of post %d", event.Type, event.Data, w.typ, w.id)
+		}
+	}
+}
@@ -1,101 +1,90 @@
 package delivery
 
 import (
-	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
 	"net/http"
 	"strconv"
 	"strings"
+	"technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/cursor"
+	"technopark-dbms-forum/internal/events"
+	"technopark-dbms-forum/internal/httpio"
 	domain "technopark-dbms-forum/internal/forum"
 	"technopark-dbms-forum/models"
 )
 
 type ForumHandler struct {
 	ForumUseCase domain.ForumUseCase
+	Auth         auth.AuthUseCase
+	Cursors      *cursor.Signer
+	Events       *events.Hub
+	Log          zerolog.Logger
 }
 
-func JSONError(message string) []byte {
-	jsonError, err := json.Marshal(models.Error{Message: message})
-	if err != nil {
-		return []byte("")
-	}
-	return jsonError
-}
-
+func NewForumHandler(r *mux.Router, forumUseCase domain.ForumUseCase, authUseCase auth.AuthUseCase, cursors *cursor.Signer, hub *events.Hub, log zerolog.Logger) {
+	handler := &ForumHandler{ForumUseCase: forumUseCase, Auth: authUseCase, Cursors: cursors, Events: hub, Log: log}
 
-func NewForumHandler(r *mux.Router, forumUseCase domain.ForumUseCase) {
-	handler := &ForumHandler{ForumUseCase: forumUseCase}
+	r.HandleFunc("/api/forum/create", handler.Forum).Methods(http.MethodPost).Name("forum_create")
+	r.HandleFunc("/api/forum/{slug}/create", handler.CreateThread).Methods(http.MethodPost).Name("thread_create")
+	r.HandleFunc("/api/forum/{slug}/details", handler.ForumInfo).Methods(http.MethodGet).Name("forum_details")
 
-	r.HandleFunc("/api/forum/create", handler.Forum).Methods(http.MethodPost)
-	r.HandleFunc("/api/forum/{slug}/create", handler.CreateThread).Methods(http.MethodPost)
-	r.HandleFunc("/api/forum/{slug}/details", handler.ForumInfo).Methods(http.MethodGet)
+	r.HandleFunc("/api/user/{nickname}/create", handler.CreateUser).Methods(http.MethodPost).Name("user_create")
+	r.HandleFunc("/api/user/{nickname}/profile", handler.ProfileUser).Methods(http.MethodGet).Name("user_profile")
+	r.HandleFunc("/api/user/{nickname}/profile", handler.ChangeProfileInformation).Methods(http.MethodPost).Name("user_profile_update")
 
-	r.HandleFunc("/api/user/{nickname}/create", handler.CreateUser).Methods(http.MethodPost)
-	r.HandleFunc("/api/user/{nickname}/profile", handler.ProfileUser).Methods(http.MethodGet)
-	r.HandleFunc("/api/user/{nickname}/profile", handler.ChangeProfileInformation).Methods(http.MethodPost)
 
+	r.HandleFunc("/api/thread/{slug_or_id}/create", handler.CreatePost).Methods(http.MethodPost).Name("posts_create")
+	r.HandleFunc("/api/thread/{slug_or_id}/details", handler.ThreadDetails).Methods(http.MethodGet).Name("thread_details")
+	r.HandleFunc("/api/thread/{slug_or_id}/posts", handler.PostsOfThread).Methods(http.MethodGet).Name("thread_posts")
+	r.HandleFunc("/api/thread/{slug_or_id}/details", handler.UpdateThread).Methods(http.MethodPost).Name("thread_update")
+	r.HandleFunc("/api/thread/{slug_or_id}", handler.DeleteThread).Methods(http.MethodDelete).Name("thread_delete")
 
-	r.HandleFunc("/api/thread/{slug_or_id}/create", handler.CreatePost).Methods(http.MethodPost)
-	r.HandleFunc("/api/thread/{slug_or_id}/details", handler.ThreadDetails).Methods(http.MethodGet)
-	r.HandleFunc("/api/thread/{slug_or_id}/posts", handler.PostsOfThread).Methods(http.MethodGet)
-	r.HandleFunc("/api/thread/{slug_or_id}/details", handler.UpdateThread).Methods(http.MethodPost)
+	r.HandleFunc("/api/service/status", handler.StatusDB).Methods(http.MethodGet).Name("service_status")
+	r.HandleFunc("/api/service/clear", handler.ClearDB).Methods(http.MethodPost).Name("service_clear")
+	r.HandleFunc("/api/forum/{slug}/clear", handler.ClearForum).Methods(http.MethodPost).Name("forum_clear")
 
-	r.HandleFunc("/api/service/status", handler.StatusDB).Methods(http.MethodGet)
-	r.HandleFunc("/api/service/clear", handler.ClearDB).Methods(http.MethodPost)
+	r.HandleFunc("/api/thread/{slug_or_id}/vote", handler.MakeVote).Methods(http.MethodPost).Name("thread_vote")
 
-	r.HandleFunc("/api/thread/{slug_or_id}/vote", handler.MakeVote).Methods(http.MethodPost)
+	r.HandleFunc("/api/post/{id}/details", handler.PostUpdate).Methods(http.MethodPost).Name("post_update")
+	r.HandleFunc("/api/post/{id}/details", handler.PostDetails).Methods(http.MethodGet).Name("post_details")
+	r.HandleFunc("/api/post/{id}", handler.DeletePost).Methods(http.MethodDelete).Name("post_delete")
+	r.HandleFunc("/api/post/{id}/history", handler.PostHistory).Methods(http.MethodGet).Name("post_history")
+	r.HandleFunc("/api/post/{id}/history/{revision}/revert", handler.RevertPost).Methods(http.MethodPost).Name("post_revert")
 
-	r.HandleFunc("/api/post/{id}/details", handler.PostUpdate).Methods(http.MethodPost)
-	r.HandleFunc("/api/post/{id}/details", handler.PostDetails).Methods(http.MethodGet)
+	r.HandleFunc("/api/forum/{slug}/threads", handler.ThreadsOfForum).Methods(http.MethodGet).Name("forum_threads")
+	r.HandleFunc("/api/forum/{slug}/users", handler.UsersOfForum).Methods(http.MethodGet).Name("forum_users")
+	r.HandleFunc("/api/forum/{slug}/search", handler.SearchForum).Methods(http.MethodGet).Name("forum_search")
+	r.HandleFunc("/api/search", handler.Search).Methods(http.MethodGet).Name("search")
 
-	r.HandleFunc("/api/forum/{slug}/threads", handler.ThreadsOfForum).Methods(http.MethodGet)
-	r.HandleFunc("/api/forum/{slug}/users", handler.UsersOfForum).Methods(http.MethodGet)
+	r.HandleFunc("/api/thread/{slug_or_id}/events", handler.ThreadEvents).Methods(http.MethodGet).Name("thread_events")
+	r.HandleFunc("/api/forum/{slug}/events", handler.ForumEvents).Methods(http.MethodGet).Name("forum_events")
 }
 
 func (f *ForumHandler) Forum(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	forum := models.Forum{}
-	err := json.NewDecoder(r.Body).Decode(&forum)
+	err := httpio.DecodeJSON(r, &forum)
+	if err == nil {
+		err = httpio.Validate(forum)
+	}
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
+		httpio.WriteError(w, err)
 		return
 	}
 
-	forum, err = f.ForumUseCase.Forum(forum)
-	status := models.GetStatusCodePost(err)
-	if status == 409 {
-
-		w.WriteHeader(models.GetStatusCodePost(err))
-
-		body, err := json.Marshal(forum)
-		if err != nil {
-
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
-
-		w.Write(body)
+	forum, err = f.ForumUseCase.Forum(r.Context(), forum)
+	if errors.Is(err, models.ErrConflict) {
+		httpio.WriteJSON(w, http.StatusConflict, forum)
 		return
 	}
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	body, err := json.Marshal(forum)
-	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
-		return
-	}
-
-	w.WriteHeader(models.GetStatusCodePost(err))
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusCreated, forum)
 }
 
 func (f *ForumHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
@@ -105,119 +94,70 @@ func (f *ForumHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
 
 
 	var thread models.Thread
-	err := json.NewDecoder(r.Body).Decode(&thread)
+	err := httpio.DecodeJSON(r, &thread)
 	if err != nil {
-
-		w.WriteHeader(http.StatusBadRequest)
+		httpio.WriteError(w, err)
 		return
 	}
 	thread.Forum = slug
 	check := thread.Slug
-
-
-
-	thread, err = f.ForumUseCase.CreatingThread(thread)
- 	status := models.GetStatusCodePost(err)
- 	if status == 409 {
-
-		w.WriteHeader(models.GetStatusCodePost(err))
-
-		body, err := json.Marshal(thread)
-		if err != nil {
-
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
-
-		w.Write(body)
+	if err := f.actAs(r, &thread.Author); err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
-
-	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+	if err := httpio.Validate(thread); err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
 
-	if check == "" {
-		threadOut := models.ThreadOut{
-			Id:      thread.Id,
-			Title:   thread.Title,
-			Author:  thread.Author,
-			Forum:   thread.Forum,
-			Message: thread.Message,
-			Votes:   thread.Votes,
-			Slug:    thread.Slug,
-			Created: thread.Created,
-		}
-		body, err := json.Marshal(threadOut)
-		if err != nil {
 
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
 
-		w.WriteHeader(models.GetStatusCodePost(err))
-		w.Write(body)
+	thread, err = f.ForumUseCase.CreatingThread(r.Context(), thread)
+	if errors.Is(err, models.ErrConflict) {
+		httpio.WriteJSON(w, http.StatusConflict, thread)
 		return
 	}
-
-	body, err := json.Marshal(thread)
 	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+	if check == "" {
+		httpio.WriteJSON(w, http.StatusCreated, models.ThreadToThreadOut(thread))
 		return
 	}
 
-	w.WriteHeader(models.GetStatusCodePost(err))
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusCreated, thread)
 }
 
 func (f *ForumHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	nickname := strings.TrimPrefix(r.URL.Path, "/api/user/")
 	nickname = strings.TrimSuffix(nickname, "/create")
-	user := models.User{}
-	err := json.NewDecoder(r.Body).Decode(&user)
+	registration := models.Registration{}
+	err := httpio.DecodeJSON(r, &registration)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
+		httpio.WriteError(w, err)
 		return
 	}
 
-	user.Nickname = nickname
-
-	users, err := f.ForumUseCase.CreateUser(user)
-	status := models.GetStatusCodePost(err)
-
-	if status == 409 {
-		body, err := json.Marshal(users)
-		if err != nil {
-
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
-		w.WriteHeader(status)
-		w.Write(body)
+	registration.Nickname = nickname
+	if err := httpio.Validate(registration); err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
-	if status == 201 {
-		body, err := json.Marshal(users[0])
-		if err != nil {
 
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
-		w.WriteHeader(status)
-		w.Write(body)
+	users, err := f.ForumUseCase.CreateUser(r.Context(), registration)
+	if errors.Is(err, models.ErrConflict) {
+		httpio.WriteJSON(w, http.StatusConflict, users)
 		return
 	}
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteJSON(w, http.StatusCreated, users[0])
 }
 
 func (f *ForumHandler) ProfileUser(w http.ResponseWriter, r *http.Request) {
@@ -226,24 +166,13 @@ func (f *ForumHandler) ProfileUser(w http.ResponseWriter, r *http.Request) {
 	nickname = strings.TrimSuffix(nickname, "/profile")
 
 
-	user, err := f.ForumUseCase.GetUser(nickname)
-	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
-		return
-	}
-
-	body, err := json.Marshal(user)
+	user, err := f.ForumUseCase.GetUser(r.Context(), nickname)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	w.WriteHeader(models.GetStatusCodeGet(err))
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, user)
 }
 
 func (f *ForumHandler) ChangeProfileInformation(w http.ResponseWriter, r *http.Request) {
@@ -253,32 +182,29 @@ func (f *ForumHandler) ChangeProfileInformation(w http.ResponseWriter, r *http.R
 
 
 	user := models.User{}
-	err := json.NewDecoder(r.Body).Decode(&user)
+	err := httpio.DecodeJSON(r, &user)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
+		httpio.WriteError(w, err)
 		return
 	}
 
 	user.Nickname = nickname
-
-	userModel, err := f.ForumUseCase.ChangeUserProfile(user)
-	if err != nil {
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+	if err := f.actAs(r, &user.Nickname); err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+	if err := httpio.ValidatePartial(user); err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
 
-	body, err := json.Marshal(userModel)
+	userModel, err := f.ForumUseCase.ChangeUserProfile(r.Context(), user)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	w.WriteHeader(models.GetStatusCodeGet(err))
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, userModel)
 }
 
 func (f *ForumHandler) ForumInfo(w http.ResponseWriter, r *http.Request) {
@@ -287,24 +213,13 @@ func (f *ForumHandler) ForumInfo(w http.ResponseWriter, r *http.Request) {
 	slug = strings.TrimSuffix(slug, "/details")
 
 
-	forum, err := f.ForumUseCase.ForumDetails(slug)
-	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
-		return
-	}
-
-	body, err := json.Marshal(forum)
+	forum, err := f.ForumUseCase.ForumDetails(r.Context(), slug)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	w.WriteHeader(models.GetStatusCodeGet(err))
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, forum)
 }
 
 func (f *ForumHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
@@ -313,20 +228,25 @@ func (f *ForumHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	slug = strings.TrimSuffix(slug, "/create")
 
 
-	thread, err := f.ForumUseCase.ThreadDetails(slug)
+	thread, err := f.ForumUseCase.ThreadDetails(r.Context(), slug)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodePost(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
 
 	var posts []models.Post
-	err = json.NewDecoder(r.Body).Decode(&posts)
+	err = httpio.DecodeJSON(r, &posts)
+	for i := range posts {
+		if err == nil {
+			err = f.actAs(r, &posts[i].Author)
+		}
+	}
+	if err == nil {
+		err = httpio.Validate(posts)
+	}
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
+		httpio.WriteError(w, err)
 		return
 	}
 
@@ -336,50 +256,13 @@ func (f *ForumHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//author := (posts)[0].Author
-	newPosts, err := f.ForumUseCase.CreatePosts(&posts, thread)
-	//if len(*newPosts) == 0 {
-	//	err = pgx.ErrNoRows
-	//}
+	newPosts, err := f.ForumUseCase.CreatePosts(r.Context(), &posts, thread)
 	if err != nil {
-
-		//if err == pgx.ErrNoRows {
-		//	//_, err = f.ForumUseCase.ThreadDetails(slug)
-		//	//if err != nil {
-		//	//
-		//	//	w.WriteHeader(models.GetStatusCodePost(err))
-		//	//	w.Write(JSONError(err.Error()))
-		//	//	return
-		//	//}
-		//	_, err = f.ForumUseCase.GetUser(author)
-		//	if err != nil {
-		//
-		//		w.WriteHeader(models.GetStatusCodePost(err))
-		//		w.Write(JSONError(err.Error()))
-		//		return
-		//	}
-		//	Err := models.ErrConflict
-		//	w.WriteHeader(models.GetStatusCodePost(Err))
-		//	w.Write(JSONError(Err.Error()))
-		//	return
-		//}
-
-		w.WriteHeader(models.GetStatusCodePost(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-
-	body, err := json.Marshal(*newPosts)
-	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
-		return
-	}
-
-	w.WriteHeader(models.GetStatusCodePost(err))
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusCreated, *newPosts)
 }
 
 func (f *ForumHandler) ThreadDetails(w http.ResponseWriter, r *http.Request) {
@@ -389,67 +272,47 @@ func (f *ForumHandler) ThreadDetails(w http.ResponseWriter, r *http.Request) {
 	slug = strings.TrimSuffix(slug, "/details")
 
 
-	thread, err := f.ForumUseCase.ThreadDetails(slug)
+	thread, err := f.ForumUseCase.ThreadDetails(r.Context(), slug)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	if models.IsUuid(thread.Slug) {
-		result := models.ThreadToThreadOut(thread)
-		body, err := json.Marshal(result)
-		if err != nil {
-
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(body)
-		return
-	}
+	httpio.WriteJSON(w, http.StatusOK, threadView(thread))
+}
 
-	body, err := json.Marshal(thread)
+func (f *ForumHandler) StatusDB(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+	exact, _ := strconv.ParseBool(query.Get("exact"))
+	perForum, _ := strconv.ParseBool(query.Get("forums"))
+	status, err := f.ForumUseCase.StatusDB(r.Context(), exact, perForum)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
-
-	w.WriteHeader(models.GetStatusCodeGet(err))
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, status)
 }
 
-func (f *ForumHandler) StatusDB(w http.ResponseWriter, r *http.Request) {
+func (f *ForumHandler) ClearDB(w http.ResponseWriter, r *http.Request)  {
 	w.Header().Set("Content-Type", "application/json")
-	status := f.ForumUseCase.StatusDB()
-	body, err := json.Marshal(status)
+	err := f.ForumUseCase.ClearDB(r.Context())
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
 	w.WriteHeader(200)
-	w.Write(body)
 }
 
-func (f *ForumHandler) ClearDB(w http.ResponseWriter, r *http.Request)  {
+func (f *ForumHandler) ClearForum(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	err := f.ForumUseCase.ClearDB()
+	cleared, err := f.ForumUseCase.ClearForum(r.Context(), mux.Vars(r)["slug"])
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
-
-	w.WriteHeader(200)
+	httpio.WriteJSON(w, http.StatusOK, cleared)
 }
 
 func (f *ForumHandler) MakeVote(w http.ResponseWriter, r *http.Request)  {
@@ -457,70 +320,42 @@ func (f *ForumHandler) MakeVote(w http.ResponseWriter, r *http.Request)  {
 	slug := strings.TrimPrefix(r.URL.Path, "/api/thread/")
 	slug = strings.TrimSuffix(slug, "/vote")
 
-	var thread models.Thread
-	id, err := strconv.Atoi(slug)
-	if err != nil {
-		thread, err = f.ForumUseCase.ThreadDetails(slug)
-	}
-
-	//thread, err := f.ForumUseCase.ThreadDetails(slug)
+	// ThreadDetails resolves numeric slugs too, and skips deleted threads.
+	thread, err := f.ForumUseCase.ThreadDetails(r.Context(), slug)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
 	var vote models.Vote
-	err = json.NewDecoder(r.Body).Decode(&vote)
-	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	err = httpio.DecodeJSON(r, &vote)
+	if err == nil {
+		err = f.actAs(r, &vote.Nickname)
 	}
-
-	if thread.Id != 0 {
-		id = thread.Id
+	if err == nil {
+		err = httpio.Validate(vote)
 	}
-
-	vote.Thread = id
-
-	thread, err = f.ForumUseCase.MakeVote(vote, thread)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
+	vote.Thread = thread.Id
 
-	thread, err = f.ForumUseCase.ThreadDetails(slug)
-
-	if models.IsUuid(thread.Slug) {
-		result := models.ThreadToThreadOut(thread)
-		body, err := json.Marshal(result)
-		if err != nil {
-
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(body)
+	thread, err = f.ForumUseCase.MakeVote(r.Context(), vote, thread)
+	if err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
 
-	body, err := json.Marshal(thread)
-	if err != nil {
 
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+	thread, err = f.ForumUseCase.ThreadDetails(r.Context(), slug)
+	if err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
 
-	w.WriteHeader(200)
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, threadView(thread))
 }
 
 func (f *ForumHandler) PostUpdate(w http.ResponseWriter, r *http.Request) {
@@ -529,39 +364,25 @@ func (f *ForumHandler) PostUpdate(w http.ResponseWriter, r *http.Request) {
 	slug = strings.TrimSuffix(slug, "/details")
 	id, err := strconv.Atoi(slug)
 	if err != nil {
-
-		w.WriteHeader(400)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, models.ErrBadRequest.WithMessage("Post id must be a number: %s", slug))
 		return
 	}
 
 	var postUpdate models.PostUpdate
-	err = json.NewDecoder(r.Body).Decode(&postUpdate)
+	err = httpio.DecodeJSON(r, &postUpdate)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
+		httpio.WriteError(w, err)
 		return
 	}
 	postUpdate.ID = id
 
-	post, err := f.ForumUseCase.UpdateMessagePost(postUpdate)
+	post, err := f.ForumUseCase.UpdateMessagePost(r.Context(), postUpdate)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodePost(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	body, err := json.Marshal(post)
-	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
-		return
-	}
-
-	w.WriteHeader(200)
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, post)
 }
 
 func (f *ForumHandler) PostDetails(w http.ResponseWriter, r *http.Request) {
@@ -570,33 +391,19 @@ func (f *ForumHandler) PostDetails(w http.ResponseWriter, r *http.Request) {
 	slug = strings.TrimSuffix(slug, "/details")
 	id, err := strconv.Atoi(slug)
 	if err != nil {
-
-		w.WriteHeader(400)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, models.ErrBadRequest.WithMessage("Post id must be a number: %s", slug))
 		return
 	}
 
 	related := r.URL.Query().Get("related")
 
-	postFull, err := f.ForumUseCase.PostFullDetails(id, related)
+	postFull, err := f.ForumUseCase.PostFullDetails(r.Context(), id, related)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-
-	body, err := json.Marshal(postFull)
-	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
-		return
-	}
-
-	w.WriteHeader(200)
-	w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, postFull)
 }
 
 func (f *ForumHandler) ThreadsOfForum(w http.ResponseWriter, r *http.Request) {
@@ -604,58 +411,31 @@ func (f *ForumHandler) ThreadsOfForum(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	var params models.Parameters
-	var err error
-	params.Limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		params.Limit = 100
-	}
-
-	params.Since = r.URL.Query().Get("since")
-
-	params.Desc, err = strconv.ParseBool(r.URL.Query().Get("desc"))
-	if err != nil {
-		params.Desc = false
-	}
-
 	slug := strings.TrimPrefix(r.URL.Path, "/api/forum/")
 	slug = strings.TrimSuffix(slug, "/threads")
 
-
-
-	threads, err := f.ForumUseCase.ListThreads(slug, params)
+	scope := "forum:" + slug + ":threads"
+	params, err := f.pageParams(r, scope)
 	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
 
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+	threads, err := f.ForumUseCase.ListThreads(r.Context(), slug, params)
+	if err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
 
 	var result []interface{}
 	for _, thr := range threads {
-		if models.IsUuid(thr.Slug) {
-			tOut := models.ThreadToThreadOut(thr)
-
-			result = append(result, tOut)
-		} else {
-			result = append(result, thr)
-		}
-	}
-
-	body, err := json.Marshal(result)
-	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
-		return
+		result = append(result, threadView(thr))
 	}
 
-	w.WriteHeader(200)
 	if len(threads) != 0 {
-		w.Write(body)
-	} else {
-		w.Write([]byte("[]"))
+		f.writeCursors(w, scope, "", params, len(threads), threadKey(threads[0]), threadKey(threads[len(threads)-1]))
 	}
+	httpio.WriteList(w, len(result), result)
 }
 
 func (f *ForumHandler) UsersOfForum(w http.ResponseWriter, r *http.Request) {
@@ -663,47 +443,26 @@ func (f *ForumHandler) UsersOfForum(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	var params models.Parameters
-	var err error
-	params.Limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		params.Limit = 100
-	}
-
-	params.Since = r.URL.Query().Get("since")
-
-	params.Desc, err = strconv.ParseBool(r.URL.Query().Get("desc"))
-	if err != nil {
-		params.Desc = false
-	}
-
 	slug := strings.TrimPrefix(r.URL.Path, "/api/forum/")
 	slug = strings.TrimSuffix(slug, "/users")
 
-
-	users, err := f.ForumUseCase.GetUsersByForum(slug, params)
+	scope := "forum:" + slug + ":users"
+	params, err := f.pageParams(r, scope)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	body, err := json.Marshal(users)
+	users, err := f.ForumUseCase.GetUsersByForum(r.Context(), slug, params)
 	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-
-	w.WriteHeader(http.StatusOK)
 	if len(users) != 0 {
-		w.Write(body)
-	} else {
-		w.Write([]byte("[]"))
+		f.writeCursors(w, scope, "", params, len(users), userKey(users[0]), userKey(users[len(users)-1]))
 	}
+	httpio.WriteList(w, len(users), users)
 }
 
 
@@ -712,56 +471,40 @@ func (f *ForumHandler) PostsOfThread(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	var params models.Parameters
-	var err error
-	params.Limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		params.Limit = 100
-	}
-
-	params.Since = r.URL.Query().Get("since")
-	sort := r.URL.Query().Get("sort")
-
-	params.Desc, err = strconv.ParseBool(r.URL.Query().Get("desc"))
-	if err != nil {
-		params.Desc = false
-	}
-
 	slug := strings.TrimPrefix(r.URL.Path, "/api/thread/")
 	slug = strings.TrimSuffix(slug, "/posts")
 
 
-	thread, err := f.ForumUseCase.ThreadDetails(slug)
+	thread, err := f.ForumUseCase.ThreadDetails(r.Context(), slug)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	posts, err := f.ForumUseCase.GetPostsOfThread(thread.Id, params, sort)
+	scope := "thread:" + strconv.Itoa(thread.Id) + ":posts"
+	params, err := f.pageParams(r, scope)
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
-	body, err := json.Marshal(posts)
-	if err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(JSONError(err.Error()))
-		return
+	sort := r.URL.Query().Get("sort")
+	if params.Cursor != nil {
+		sort = params.Cursor.Sort
+	}
+	if sort != "tree" && sort != "parent_tree" {
+		sort = "flat"
 	}
 
-	w.WriteHeader(200)
-	if len(posts) != 0 {
-		w.Write(body)
-	} else {
-		w.Write([]byte("[]"))
+	posts, err := f.ForumUseCase.GetPostsOfThread(r.Context(), thread.Id, params, sort)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
 	}
 
+	size, first, last := postPage(posts, sort)
+	f.writeCursors(w, scope, sort, params, size, first, last)
+	httpio.WriteList(w, len(posts), posts)
 }
 
 func (f *ForumHandler) UpdateThread(w http.ResponseWriter, r *http.Request) {
@@ -769,11 +512,12 @@ func (f *ForumHandler) UpdateThread(w http.ResponseWriter, r *http.Request) {
 	slugOrId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/thread/"), "/details")
 
 	var thread models.Thread
-	err := json.NewDecoder(r.Body).Decode(&thread)
+	err := httpio.DecodeJSON(r, &thread)
+	if err == nil {
+		err = httpio.ValidatePartial(thread)
+	}
 	if err != nil {
-
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+		httpio.WriteError(w, err)
 		return
 	}
 
@@ -784,38 +528,82 @@ func (f *ForumHandler) UpdateThread(w http.ResponseWriter, r *http.Request) {
 		thread.Id = id
 	}
 
-	thread, err = f.ForumUseCase.UpdateThread(thread)
+	thread, err = f.ForumUseCase.UpdateThread(r.Context(), thread)
 	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteJSON(w, http.StatusOK, threadView(thread))
+}
 
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+func (f *ForumHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	slug := strings.TrimPrefix(r.URL.Path, "/api/post/")
+	id, err := strconv.Atoi(slug)
+	if err != nil {
+		httpio.WriteError(w, models.ErrBadRequest.WithMessage("Post id must be a number: %s", slug))
 		return
 	}
 
-	if models.IsUuid(thread.Slug) {
-		result := models.ThreadToThreadOut(thread)
-		body, err := json.Marshal(result)
-		if err != nil {
+	if err := f.ForumUseCase.DeletePost(r.Context(), id); err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
 
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(JSONError(err.Error()))
-			return
-		}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *ForumHandler) PostHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	slug := strings.TrimPrefix(r.URL.Path, "/api/post/")
+	slug = strings.TrimSuffix(slug, "/history")
+	id, err := strconv.Atoi(slug)
+	if err != nil {
+		httpio.WriteError(w, models.ErrBadRequest.WithMessage("Post id must be a number: %s", slug))
+		return
+	}
+
+	revisions, err := f.ForumUseCase.PostHistory(r.Context(), id)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
 
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(body)
+	httpio.WriteJSON(w, http.StatusOK, revisions)
+}
 
+func (f *ForumHandler) RevertPost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		httpio.WriteError(w, models.ErrBadRequest.WithMessage("Post id must be a number: %s", vars["id"]))
+		return
+	}
+	revision, err := strconv.Atoi(vars["revision"])
+	if err != nil {
+		httpio.WriteError(w, models.ErrBadRequest.WithMessage("Revision id must be a number: %s", vars["revision"]))
 		return
 	}
 
-	body, err := json.Marshal(thread)
+	post, err := f.ForumUseCase.RevertPost(r.Context(), id, revision)
 	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteJSON(w, http.StatusOK, post)
+}
 
-		w.WriteHeader(models.GetStatusCodeGet(err))
-		w.Write(JSONError(err.Error()))
+func (f *ForumHandler) DeleteThread(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	slugOrId := strings.TrimPrefix(r.URL.Path, "/api/thread/")
+
+	if err := f.ForumUseCase.DeleteThread(r.Context(), slugOrId); err != nil {
+		httpio.WriteError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(body)
-}
\ No newline at end of file
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"technopark-dbms-forum/internal/events"
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/internal/logging"
+	"technopark-dbms-forum/models"
+)
+
+func (f *ForumHandler) ThreadEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	slug := strings.TrimPrefix(r.URL.Path, "/api/thread/")
+	slug = strings.TrimSuffix(slug, "/events")
+
+	thread, err := f.ForumUseCase.ThreadDetails(r.Context(), slug)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+	f.stream(w, r, events.ThreadTopic(thread.Id))
+}
+
+func (f *ForumHandler) ForumEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	slug := strings.TrimPrefix(r.URL.Path, "/api/forum/")
+	slug = strings.TrimSuffix(slug, "/events")
+
+	forum, err := f.ForumUseCase.ForumDetails(r.Context(), slug)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+	f.stream(w, r, events.ForumTopic(forum.Slug))
+}
+
+// stream writes the events of topic as Server-Sent Events until the client
+// goes away or the stream lifetime is over. Clients reconnect by themselves.
+func (f *ForumHandler) stream(w http.ResponseWriter, r *http.Request, topic string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpio.WriteError(w, models.ErrInternalServerError.WithMessage("Streaming is not supported"))
+		return
+	}
+
+	subscription, cancel := f.Events.Subscribe(topic)
+	defer cancel()
+
+	cfg := f.Events.Config()
+	heartbeat := time.NewTicker(cfg.Heartbeat)
+	defer heartbeat.Stop()
+	var end <-chan time.Time
+	if cfg.Lifetime > 0 {
+		timer := time.NewTimer(cfg.Lifetime)
+		defer timer.Stop()
+		end = timer.C
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 1000\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-end:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-subscription:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				logging.For(r.Context(), f.Log).Error().Err(err).Str("topic", topic).Msg("encoding event")
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,16 @@
+package delivery
+
+import (
+	"net/http"
+
+	"technopark-dbms-forum/internal/auth"
+)
+
+// actAs fills an empty nickname from the session and checks the caller may
+// act as the resulting user.
+func (f *ForumHandler) actAs(r *http.Request, nickname *string) error {
+	if identity, ok := auth.Nickname(r.Context()); ok && *nickname == "" {
+		*nickname = identity
+	}
+	return f.Auth.Authorize(r.Context(), *nickname)
+}
@@ -0,0 +1,91 @@
+package delivery
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/models"
+)
+
+const adminTokenHeader = "X-Admin-Token"
+
+// TimeoutMiddleware bounds the request context with the deadline configured
+// for the matched route name, falling back to defaultTimeout. A zero timeout
+// leaves the context without a deadline.
+func TimeoutMiddleware(defaultTimeout time.Duration, routeTimeouts map[string]time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := defaultTimeout
+			if route := mux.CurrentRoute(r); route != nil {
+				if routeTimeout, ok := routeTimeouts[route.GetName()]; ok {
+					timeout = routeTimeout
+				}
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// BodyLimitMiddleware caps request bodies at maxBytes; httpio.DecodeJSON reports
+// the overflow as 413. The unlimited routes read their bodies as a stream.
+func BodyLimitMiddleware(maxBytes int64, unlimited ...string) mux.MiddlewareFunc {
+	exempt := make(map[string]bool, len(unlimited))
+	for _, route := range unlimited {
+		exempt[route] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil && exempt[route.GetName()] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminMiddleware guards the named routes: they answer 403 unless enabled and
+// 401 without the admin token.
+func AdminMiddleware(enabled bool, token string, routes ...string) mux.MiddlewareFunc {
+	guarded := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		guarded[route] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil || !guarded[route.GetName()] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if !enabled {
+				httpio.WriteError(w, models.ErrForbidden.WithMessage("Route %s is disabled", route.GetName()))
+				return
+			}
+			given := r.Header.Get(adminTokenHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				httpio.WriteError(w, models.ErrUnauthorized.WithMessage("Admin token is missing or wrong"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
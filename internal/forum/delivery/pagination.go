@@ -0,0 +1,111 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"technopark-dbms-forum/models"
+)
+
+const (
+	nextCursorHeader = "X-Next-Cursor"
+	prevCursorHeader = "X-Prev-Cursor"
+)
+
+// pageParams reads limit, since and desc from the query. A cursor parameter
+// replaces since and desc with the ones it was issued for; limit may still
+// be changed between pages.
+func (f *ForumHandler) pageParams(r *http.Request, scope string) (models.Parameters, error) {
+	query := r.URL.Query()
+
+	var params models.Parameters
+	var err error
+	params.Limit, err = strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		params.Limit = 100
+	}
+
+	params.Since = query.Get("since")
+
+	params.Desc, err = strconv.ParseBool(query.Get("desc"))
+	if err != nil {
+		params.Desc = false
+	}
+
+	token := query.Get("cursor")
+	if token == "" {
+		return params, nil
+	}
+	cursor, err := f.Cursors.Decode(token, scope)
+	if err != nil {
+		return models.Parameters{}, err
+	}
+	params.Since = ""
+	params.Desc = cursor.Desc
+	if query.Get("limit") == "" {
+		params.Limit = cursor.Limit
+	}
+	params.Cursor = &cursor
+	return params, nil
+}
+
+// writeCursors sets the next and previous cursors of a page. size counts the
+// units the limit applies to and first/last are the sort keys at both ends.
+// A full page may have a successor; a page reached through since or a
+// cursor has a predecessor.
+func (f *ForumHandler) writeCursors(w http.ResponseWriter, scope, sort string, params models.Parameters, size int, first, last []string) {
+	if size == 0 {
+		return
+	}
+
+	backward := params.Cursor != nil && params.Cursor.Backward
+	continued := params.Cursor != nil || params.Since != ""
+	full := params.Limit > 0 && size >= params.Limit
+
+	base := models.Cursor{Scope: scope, Sort: sort, Desc: params.Desc, Limit: params.Limit}
+	if full || backward {
+		next := base
+		next.Key = last
+		w.Header().Set(nextCursorHeader, f.Cursors.Encode(next))
+	}
+	if (full && backward) || (!backward && continued) {
+		prev := base
+		prev.Key = first
+		prev.Backward = true
+		w.Header().Set(prevCursorHeader, f.Cursors.Encode(prev))
+	}
+}
+
+func threadKey(thread models.Thread) []string {
+	return []string{thread.Created.Format(time.RFC3339Nano), strconv.Itoa(thread.Id)}
+}
+
+func userKey(user models.User) []string {
+	return []string{user.Nickname}
+}
+
+func postKey(post models.Post) []string {
+	return []string{strconv.Itoa(post.ID)}
+}
+
+// postPage returns the page size and end keys of a posts page. parent_tree
+// pages are counted and keyed by their root posts.
+func postPage(posts []models.Post, sort string) (int, []string, []string) {
+	if len(posts) == 0 {
+		return 0, nil, nil
+	}
+	if sort != "parent_tree" {
+		return len(posts), postKey(posts[0]), postKey(posts[len(posts)-1])
+	}
+
+	roots := 0
+	var last models.Post
+	for _, post := range posts {
+		if !post.Parent.Valid {
+			roots++
+			last = post
+		}
+	}
+	return roots, postKey(posts[0]), postKey(last)
+}
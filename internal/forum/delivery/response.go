@@ -0,0 +1,13 @@
+package delivery
+
+import (
+	"technopark-dbms-forum/models"
+)
+
+// threadView hides generated uuid slugs, which clients never sent.
+func threadView(thread models.Thread) interface{} {
+	if models.IsUuid(thread.Slug) {
+		return models.ThreadToThreadOut(thread)
+	}
+	return thread
+}
@@ -0,0 +1,107 @@
+package delivery
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/models"
+)
+
+func (f *ForumHandler) SearchForum(w http.ResponseWriter, r *http.Request) {
+	slug := strings.TrimPrefix(r.URL.Path, "/api/forum/")
+	slug = strings.TrimSuffix(slug, "/search")
+	f.search(w, r, slug)
+}
+
+func (f *ForumHandler) Search(w http.ResponseWriter, r *http.Request) {
+	f.search(w, r, "")
+}
+
+func (f *ForumHandler) search(w http.ResponseWriter, r *http.Request, forum string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	search, err := searchQuery(r, forum)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	scope := searchScope(search)
+	params, err := f.pageParams(r, scope)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+	// Results come best first unless desc=false asks for the reverse.
+	if params.Cursor == nil && !r.URL.Query().Has("desc") {
+		params.Desc = true
+	}
+
+	results, err := f.ForumUseCase.Search(r.Context(), search, params)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	if len(results) != 0 {
+		f.writeCursors(w, scope, "", params, len(results), searchKey(results[0]), searchKey(results[len(results)-1]))
+	}
+	httpio.WriteList(w, len(results), results)
+}
+
+func searchQuery(r *http.Request, forum string) (models.Search, error) {
+	query := r.URL.Query()
+	if since := query.Get("since"); since != "" {
+		if _, err := strconv.ParseFloat(since, 32); err != nil {
+			return models.Search{}, models.ErrBadRequest.WithMessage("since must be a rank: %s", since)
+		}
+	}
+	search := models.Search{
+		Query:  query.Get("q"),
+		Type:   query.Get("type"),
+		Forum:  forum,
+		Author: query.Get("author"),
+		Thread: query.Get("thread"),
+	}
+	if err := httpio.Validate(&search); err != nil {
+		return search, err
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		if search.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return search, models.ErrBadRequest.WithMessage("from must be an RFC 3339 time: %s", from)
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if search.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return search, models.ErrBadRequest.WithMessage("to must be an RFC 3339 time: %s", to)
+		}
+	}
+	return search, nil
+}
+
+// searchScope binds cursors to the query and filters they were issued for.
+func searchScope(search models.Search) string {
+	values := url.Values{}
+	values.Set("q", search.Query)
+	values.Set("type", search.Type)
+	values.Set("forum", search.Forum)
+	values.Set("author", search.Author)
+	values.Set("thread", search.Thread)
+	if !search.From.IsZero() {
+		values.Set("from", search.From.Format(time.RFC3339))
+	}
+	if !search.To.IsZero() {
+		values.Set("to", search.To.Format(time.RFC3339))
+	}
+	return "search:" + values.Encode()
+}
+
+func searchKey(result models.SearchResult) []string {
+	return []string{strconv.FormatFloat(float64(result.Rank), 'g', -1, 32), result.Type, strconv.Itoa(result.ID)}
+}
@@ -1,63 +1,77 @@
 package forum
 
 import (
-	"github.com/jackc/pgx"
+	"context"
+	"github.com/jackc/pgx/v4"
 	"technopark-dbms-forum/models"
 )
 
 type ForumUseCase interface {
-	Forum(forum models.Forum) (models.Forum, error)
-	CreateUser(user models.User) ([]models.User, error)
-	GetUser(nickname string) (models.User, error)
-	ChangeUserProfile(user models.User) (models.User, error)
-	ForumDetails(slug string) (models.Forum, error)
-	CreatingThread(thread models.Thread) (models.Thread, error)
-	CreatePosts(posts *[]models.Post, thread models.Thread) (*[]models.Post, error)
-	ThreadDetails(slug string) (models.Thread, error)
-	StatusDB() models.Status
-	ClearDB() error
-	MakeVote(vote models.Vote, thread models.Thread) (models.Thread, error)
-	SumVotesInThread(id int) int
-	UpdateMessagePost(update models.PostUpdate) (models.Post, error)
-	PostFullDetails(id int, related string) (models.PostFull, error)
-	ListThreads(slug string, params models.Parameters) ([]models.Thread, error)
-	GetUsersByForum(slug string, params models.Parameters) ([]models.User, error)
-	GetPostsOfThread(threadId int, parameters models.Parameters, sort string) ([]models.Post, error)
-	UpdateThread(thread models.Thread) (models.Thread, error)
+	Forum(ctx context.Context, forum models.Forum) (models.Forum, error)
+	CreateUser(ctx context.Context, registration models.Registration) ([]models.User, error)
+	GetUser(ctx context.Context, nickname string) (models.User, error)
+	ChangeUserProfile(ctx context.Context, user models.User) (models.User, error)
+	ForumDetails(ctx context.Context, slug string) (models.Forum, error)
+	CreatingThread(ctx context.Context, thread models.Thread) (models.Thread, error)
+	CreatePosts(ctx context.Context, posts *[]models.Post, thread models.Thread) (*[]models.Post, error)
+	ThreadDetails(ctx context.Context, slug string) (models.Thread, error)
+	StatusDB(ctx context.Context, exact bool, perForum bool) (models.Status, error)
+	ClearDB(ctx context.Context) error
+	ClearForum(ctx context.Context, slug string) (models.ClearedForum, error)
+	MakeVote(ctx context.Context, vote models.Vote, thread models.Thread) (models.Thread, error)
+	SumVotesInThread(ctx context.Context, id int) int
+	UpdateMessagePost(ctx context.Context, update models.PostUpdate) (models.Post, error)
+	PostFullDetails(ctx context.Context, id int, related string) (models.PostFull, error)
+	ListThreads(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error)
+	GetUsersByForum(ctx context.Context, slug string, params models.Parameters) ([]models.User, error)
+	GetPostsOfThread(ctx context.Context, threadId int, parameters models.Parameters, sort string) ([]models.Post, error)
+	UpdateThread(ctx context.Context, thread models.Thread) (models.Thread, error)
+	DeletePost(ctx context.Context, id int) error
+	DeleteThread(ctx context.Context, slugOrId string) error
+	PostHistory(ctx context.Context, id int) ([]models.PostRevision, error)
+	RevertPost(ctx context.Context, id int, revision int) (models.Post, error)
+	Search(ctx context.Context, search models.Search, params models.Parameters) ([]models.SearchResult, error)
 }
 
 type ForumRepository interface {
-	InsertForum(forum models.Forum) error
-	CheckForum(forum models.Forum) (models.Forum, bool)
-	SelectUsers(user models.User) ([]models.User, error)
-	InsertUser(user models.User) error
-	SelectUser(user string) (models.User, error)
-	SelectUserByEmail(user models.User) (models.User, error)
-	UpdateUserInfo(user models.User) (models.User, error)
-	SelectForum(forumName string) (models.Forum, error)
-	SelectThreadBySlug(slug string) (models.Thread, error)
-	InsertThread(thread models.Thread) (models.Thread,error)
-	SelectThreadById(id int) (models.Thread, error)
-	CheckParent(post models.Post) bool
-	InsertPost(post models.Post) (models.Post, error)
-	StatusOfForum() models.Status
-	ClearDB() error
-	SelectVote(vote models.Vote) (models.Vote, error)
-	UpdateVote(vote models.Vote) (models.Vote, error)
-	InsertVote(vote models.Vote)  error
-	SumVotesInThread(id int) int
-	SelectPost(id int) (models.Post, error)
-	UpdatePost(post models.Post, postUpdate models.PostUpdate) (models.Post, error)
-	SelectThreads(slug string, params models.Parameters) ([]models.Thread, error)
-	SelectUsersByForum(slug string, params models.Parameters) ([]models.User, error)
-	PostParentTreeSort(threadId int, parameters models.Parameters) ([]models.Post, error)
-	PostTreeSort(threadId int, parameters models.Parameters) ([]models.Post, error)
-	PostFlatSort(id int, parameters models.Parameters) ([]models.Post, error)
-	UpdateThread(thread models.Thread) (models.Thread, error)
-	NewTransaction() (*pgx.Tx, error)
-	Rollback(tx *pgx.Tx)
-	InsertPosts(posts *[]models.Post, thread models.Thread) (*[]models.Post, error)
-	SelectNickById(userId int) string
-	AutocommitOff()
-	AutocommitOn()
+	InsertForum(ctx context.Context, forum models.Forum) error
+	CheckForum(ctx context.Context, forum models.Forum) (models.Forum, bool)
+	SelectUsers(ctx context.Context, user models.User) ([]models.User, error)
+	InsertUser(ctx context.Context, user models.User) error
+	SelectUser(ctx context.Context, user string) (models.User, error)
+	SelectUserByEmail(ctx context.Context, user models.User) (models.User, error)
+	UpdateUserInfo(ctx context.Context, user models.User) (models.User, error)
+	SelectForum(ctx context.Context, forumName string) (models.Forum, error)
+	SelectThreadBySlug(ctx context.Context, slug string) (models.Thread, error)
+	InsertThread(ctx context.Context, thread models.Thread) (models.Thread,error)
+	SelectThreadById(ctx context.Context, id int) (models.Thread, error)
+	CheckParent(ctx context.Context, post models.Post) bool
+	InsertPost(ctx context.Context, post models.Post) (models.Post, error)
+	StatusOfForum(ctx context.Context, exact bool) (models.Status, error)
+	StatusOfForums(ctx context.Context, exact bool) ([]models.ForumStatus, error)
+	ClearDB(ctx context.Context) error
+	ClearForum(ctx context.Context, slug string) (models.ClearedForum, error)
+	SelectVote(ctx context.Context, vote models.Vote) (models.Vote, error)
+	UpdateVote(ctx context.Context, vote models.Vote) (models.Vote, error)
+	InsertVote(ctx context.Context, vote models.Vote)  error
+	SumVotesInThread(ctx context.Context, id int) int
+	SelectPost(ctx context.Context, id int) (models.Post, error)
+	SelectPostRange(ctx context.Context, thread int, first int, last int) ([]models.Post, error)
+	UpdatePost(ctx context.Context, post models.Post, postUpdate models.PostUpdate) (models.Post, error)
+	SelectThreads(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error)
+	SelectUsersByForum(ctx context.Context, slug string, params models.Parameters) ([]models.User, error)
+	PostParentTreeSort(ctx context.Context, threadId int, parameters models.Parameters) ([]models.Post, error)
+	PostTreeSort(ctx context.Context, threadId int, parameters models.Parameters) ([]models.Post, error)
+	PostFlatSort(ctx context.Context, id int, parameters models.Parameters) ([]models.Post, error)
+	UpdateThread(ctx context.Context, thread models.Thread) (models.Thread, error)
+	DeletePost(ctx context.Context, id int) error
+	DeleteThread(ctx context.Context, id int) error
+	SelectRevisions(ctx context.Context, postId int) ([]models.PostRevision, error)
+	SelectRevision(ctx context.Context, postId int, id int) (models.PostRevision, error)
+	CountRevisions(ctx context.Context, postId int) (int, error)
+	Search(ctx context.Context, search models.Search, params models.Parameters) ([]models.SearchResult, error)
+	NewTransaction(ctx context.Context) (pgx.Tx, error)
+	Rollback(ctx context.Context, tx pgx.Tx)
+	InsertPosts(ctx context.Context, posts *[]models.Post, thread models.Thread) (*[]models.Post, error)
+	SelectNickById(ctx context.Context, userId int) string
 }
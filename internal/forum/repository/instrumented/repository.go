@@ -0,0 +1,307 @@
+package instrumented
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	domain "technopark-dbms-forum/internal/forum"
+	"technopark-dbms-forum/internal/metrics"
+	"technopark-dbms-forum/models"
+)
+
+// forumRepository times every call of the wrapped repository and counts
+// created posts, threads and votes. Transaction plumbing is passed through.
+type forumRepository struct {
+	next    domain.ForumRepository
+	metrics *metrics.Metrics
+}
+
+func NewForumRepository(next domain.ForumRepository, m *metrics.Metrics) domain.ForumRepository {
+	return &forumRepository{next: next, metrics: m}
+}
+
+func (r *forumRepository) InsertForum(ctx context.Context, forum models.Forum) error {
+	done := r.metrics.Query("forum", "InsertForum")
+	err := r.next.InsertForum(ctx, forum)
+	done(err)
+	return err
+}
+
+func (r *forumRepository) CheckForum(ctx context.Context, forum models.Forum) (models.Forum, bool) {
+	done := r.metrics.Query("forum", "CheckForum")
+	result, ok := r.next.CheckForum(ctx, forum)
+	done(nil)
+	return result, ok
+}
+
+func (r *forumRepository) SelectUsers(ctx context.Context, user models.User) ([]models.User, error) {
+	done := r.metrics.Query("forum", "SelectUsers")
+	result, err := r.next.SelectUsers(ctx, user)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) InsertUser(ctx context.Context, user models.User) error {
+	done := r.metrics.Query("forum", "InsertUser")
+	err := r.next.InsertUser(ctx, user)
+	done(err)
+	return err
+}
+
+func (r *forumRepository) SelectUser(ctx context.Context, user string) (models.User, error) {
+	done := r.metrics.Query("forum", "SelectUser")
+	result, err := r.next.SelectUser(ctx, user)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) SelectUserByEmail(ctx context.Context, user models.User) (models.User, error) {
+	done := r.metrics.Query("forum", "SelectUserByEmail")
+	result, err := r.next.SelectUserByEmail(ctx, user)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) UpdateUserInfo(ctx context.Context, user models.User) (models.User, error) {
+	done := r.metrics.Query("forum", "UpdateUserInfo")
+	result, err := r.next.UpdateUserInfo(ctx, user)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) SelectForum(ctx context.Context, forumName string) (models.Forum, error) {
+	done := r.metrics.Query("forum", "SelectForum")
+	result, err := r.next.SelectForum(ctx, forumName)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) SelectThreadBySlug(ctx context.Context, slug string) (models.Thread, error) {
+	done := r.metrics.Query("forum", "SelectThreadBySlug")
+	result, err := r.next.SelectThreadBySlug(ctx, slug)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) InsertThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	done := r.metrics.Query("forum", "InsertThread")
+	result, err := r.next.InsertThread(ctx, thread)
+	done(err)
+	if err == nil {
+		r.metrics.ThreadsCreated.Inc()
+	}
+	return result, err
+}
+
+func (r *forumRepository) SelectThreadById(ctx context.Context, id int) (models.Thread, error) {
+	done := r.metrics.Query("forum", "SelectThreadById")
+	result, err := r.next.SelectThreadById(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) CheckParent(ctx context.Context, post models.Post) bool {
+	done := r.metrics.Query("forum", "CheckParent")
+	ok := r.next.CheckParent(ctx, post)
+	done(nil)
+	return ok
+}
+
+func (r *forumRepository) InsertPost(ctx context.Context, post models.Post) (models.Post, error) {
+	done := r.metrics.Query("forum", "InsertPost")
+	result, err := r.next.InsertPost(ctx, post)
+	done(err)
+	if err == nil {
+		r.metrics.PostsCreated.Inc()
+	}
+	return result, err
+}
+
+func (r *forumRepository) StatusOfForum(ctx context.Context, exact bool) (models.Status, error) {
+	done := r.metrics.Query("forum", "StatusOfForum")
+	result, err := r.next.StatusOfForum(ctx, exact)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) StatusOfForums(ctx context.Context, exact bool) ([]models.ForumStatus, error) {
+	done := r.metrics.Query("forum", "StatusOfForums")
+	result, err := r.next.StatusOfForums(ctx, exact)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) ClearForum(ctx context.Context, slug string) (models.ClearedForum, error) {
+	done := r.metrics.Query("forum", "ClearForum")
+	result, err := r.next.ClearForum(ctx, slug)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) ClearDB(ctx context.Context) error {
+	done := r.metrics.Query("forum", "ClearDB")
+	err := r.next.ClearDB(ctx)
+	done(err)
+	return err
+}
+
+func (r *forumRepository) SelectVote(ctx context.Context, vote models.Vote) (models.Vote, error) {
+	done := r.metrics.Query("forum", "SelectVote")
+	result, err := r.next.SelectVote(ctx, vote)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) UpdateVote(ctx context.Context, vote models.Vote) (models.Vote, error) {
+	done := r.metrics.Query("forum", "UpdateVote")
+	result, err := r.next.UpdateVote(ctx, vote)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) InsertVote(ctx context.Context, vote models.Vote) error {
+	done := r.metrics.Query("forum", "InsertVote")
+	err := r.next.InsertVote(ctx, vote)
+	done(err)
+	if err == nil {
+		r.metrics.VotesCreated.Inc()
+	}
+	return err
+}
+
+func (r *forumRepository) SumVotesInThread(ctx context.Context, id int) int {
+	done := r.metrics.Query("forum", "SumVotesInThread")
+	result := r.next.SumVotesInThread(ctx, id)
+	done(nil)
+	return result
+}
+
+func (r *forumRepository) SelectPost(ctx context.Context, id int) (models.Post, error) {
+	done := r.metrics.Query("forum", "SelectPost")
+	result, err := r.next.SelectPost(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) SelectPostRange(ctx context.Context, thread int, first int, last int) ([]models.Post, error) {
+	done := r.metrics.Query("forum", "SelectPostRange")
+	result, err := r.next.SelectPostRange(ctx, thread, first, last)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) UpdatePost(ctx context.Context, post models.Post, postUpdate models.PostUpdate) (models.Post, error) {
+	done := r.metrics.Query("forum", "UpdatePost")
+	result, err := r.next.UpdatePost(ctx, post, postUpdate)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) SelectThreads(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error) {
+	done := r.metrics.Query("forum", "SelectThreads")
+	result, err := r.next.SelectThreads(ctx, slug, params)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) SelectUsersByForum(ctx context.Context, slug string, params models.Parameters) ([]models.User, error) {
+	done := r.metrics.Query("forum", "SelectUsersByForum")
+	result, err := r.next.SelectUsersByForum(ctx, slug, params)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) PostParentTreeSort(ctx context.Context, threadId int, parameters models.Parameters) ([]models.Post, error) {
+	done := r.metrics.Query("forum", "PostParentTreeSort")
+	result, err := r.next.PostParentTreeSort(ctx, threadId, parameters)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) PostTreeSort(ctx context.Context, threadId int, parameters models.Parameters) ([]models.Post, error) {
+	done := r.metrics.Query("forum", "PostTreeSort")
+	result, err := r.next.PostTreeSort(ctx, threadId, parameters)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) PostFlatSort(ctx context.Context, id int, parameters models.Parameters) ([]models.Post, error) {
+	done := r.metrics.Query("forum", "PostFlatSort")
+	result, err := r.next.PostFlatSort(ctx, id, parameters)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) UpdateThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	done := r.metrics.Query("forum", "UpdateThread")
+	result, err := r.next.UpdateThread(ctx, thread)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) DeletePost(ctx context.Context, id int) error {
+	done := r.metrics.Query("forum", "DeletePost")
+	err := r.next.DeletePost(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *forumRepository) DeleteThread(ctx context.Context, id int) error {
+	done := r.metrics.Query("forum", "DeleteThread")
+	err := r.next.DeleteThread(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *forumRepository) SelectRevisions(ctx context.Context, postId int) ([]models.PostRevision, error) {
+	done := r.metrics.Query("forum", "SelectRevisions")
+	result, err := r.next.SelectRevisions(ctx, postId)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) SelectRevision(ctx context.Context, postId int, id int) (models.PostRevision, error) {
+	done := r.metrics.Query("forum", "SelectRevision")
+	result, err := r.next.SelectRevision(ctx, postId, id)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) CountRevisions(ctx context.Context, postId int) (int, error) {
+	done := r.metrics.Query("forum", "CountRevisions")
+	result, err := r.next.CountRevisions(ctx, postId)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) Search(ctx context.Context, search models.Search, params models.Parameters) ([]models.SearchResult, error) {
+	done := r.metrics.Query("forum", "Search")
+	result, err := r.next.Search(ctx, search, params)
+	done(err)
+	return result, err
+}
+
+func (r *forumRepository) NewTransaction(ctx context.Context) (pgx.Tx, error) {
+	return r.next.NewTransaction(ctx)
+}
+
+func (r *forumRepository) Rollback(ctx context.Context, tx pgx.Tx) {
+	r.next.Rollback(ctx, tx)
+}
+
+func (r *forumRepository) InsertPosts(ctx context.Context, posts *[]models.Post, thread models.Thread) (*[]models.Post, error) {
+	done := r.metrics.Query("forum", "InsertPosts")
+	result, err := r.next.InsertPosts(ctx, posts, thread)
+	done(err)
+	if err == nil && result != nil {
+		r.metrics.PostsCreated.Add(float64(len(*result)))
+	}
+	return result, err
+}
+
+func (r *forumRepository) SelectNickById(ctx context.Context, userId int) string {
+	done := r.metrics.Query("forum", "SelectNickById")
+	result := r.next.SelectNickById(ctx, userId)
+	done(nil)
+	return result
+}
@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/models"
+)
+
+// keyset returns the comparison and the direction that continue a listing
+// after the cursor row. Backward cursors walk the listing in reverse and
+// the page is flipped back afterwards.
+func keyset(desc bool, cursor *models.Cursor) (string, string) {
+	if desc != cursor.Backward {
+		return "<", "DESC"
+	}
+	return ">", "ASC"
+}
+
+func cursorId(cursor *models.Cursor) (int, error) {
+	id, err := strconv.Atoi(cursor.Key[0])
+	if err != nil {
+		return 0, models.ErrInvalidCursor
+	}
+	return id, nil
+}
+
+func (p *postgresForumRepository) selectThreadsAfter(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error) {
+	var threads []models.Thread
+	if len(params.Cursor.Key) != 2 {
+		return threads, models.ErrInvalidCursor
+	}
+	created, err := time.Parse(time.RFC3339Nano, params.Cursor.Key[0])
+	if err != nil {
+		return threads, models.ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(params.Cursor.Key[1])
+	if err != nil {
+		return threads, models.ErrInvalidCursor
+	}
+
+	cmp, dir := keyset(params.Desc, params.Cursor)
+	rows, err := p.Conn.Query(ctx, fmt.Sprintf(`SELECT id, author, created, forum, message, slug, title, votes FROM thread
+		WHERE forum=$1 AND NOT isDeleted AND (created, id) %s ($2, $3) ORDER BY created %s, id %s LIMIT $4;`, cmp, dir, dir),
+		slug, created, id, params.Limit)
+	if err != nil {
+		return threads, database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var thread models.Thread
+		err = rows.Scan(&thread.Id, &thread.Author, &thread.Created, &thread.Forum, &thread.Message,
+			&thread.Slug, &thread.Title, &thread.Votes)
+		if err != nil {
+			return threads, database.Translate(err)
+		}
+		threads = append(threads, thread)
+	}
+
+	if params.Cursor.Backward {
+		for i, j := 0, len(threads)-1; i < j; i, j = i+1, j-1 {
+			threads[i], threads[j] = threads[j], threads[i]
+		}
+	}
+	return threads, database.Translate(rows.Err())
+}
+
+func (p *postgresForumRepository) selectUsersByForumAfter(ctx context.Context, slug string, params models.Parameters) ([]models.User, error) {
+	var users []models.User
+	cmp, dir := keyset(params.Desc, params.Cursor)
+	rows, err := p.Conn.Query(ctx, fmt.Sprintf(`SELECT about, email, fullname, nickname
+		FROM users_forum WHERE slug=$1 AND nickname %s $2
+		ORDER BY nickname %s LIMIT NULLIF($3, 0)`, cmp, dir), slug, params.Cursor.Key[0], params.Limit)
+	if err != nil {
+		return users, database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.User
+		err = rows.Scan(&u.About, &u.Email, &u.FullName, &u.Nickname)
+		if err != nil {
+			return users, database.Translate(err)
+		}
+		users = append(users, u)
+	}
+
+	if params.Cursor.Backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+	return users, database.Translate(rows.Err())
+}
+
+// postsAfter serves the flat and tree sorts. Both are ordered by a single
+// unique key: the id for flat and the materialised path for tree.
+func (p *postgresForumRepository) postsAfter(ctx context.Context, threadId int, params models.Parameters, tree bool) ([]models.Post, error) {
+	var posts []models.Post
+	id, err := cursorId(params.Cursor)
+	if err != nil {
+		return posts, err
+	}
+
+	cmp, dir := keyset(params.Desc, params.Cursor)
+	var rows pgx.Rows
+	if tree {
+		rows, err = p.Conn.Query(ctx, fmt.Sprintf(`SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
+		WHERE thread=$1 AND path %s (SELECT path FROM post WHERE id = $2)
+		ORDER BY path %s, id %s LIMIT $3;`, cmp, dir, dir), threadId, id, params.Limit)
+	} else {
+		rows, err = p.Conn.Query(ctx, fmt.Sprintf(`SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
+		WHERE thread=$1 AND id %s $2 ORDER BY id %s LIMIT $3;`, cmp, dir), threadId, id, params.Limit)
+	}
+	if err != nil {
+		return posts, database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var post models.Post
+		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message, &post.Parent, &post.Thread, &post.IsDeleted)
+		if err != nil {
+			return posts, database.Translate(err)
+		}
+		posts = append(posts, post)
+	}
+
+	if params.Cursor.Backward {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+	return posts, database.Translate(rows.Err())
+}
+
+// parentTreeAfter pages by root posts: the cursor holds the id of a root and
+// every page carries whole subtrees, always in the listing's own order.
+func (p *postgresForumRepository) parentTreeAfter(ctx context.Context, threadId int, params models.Parameters) ([]models.Post, error) {
+	var posts []models.Post
+	id, err := cursorId(params.Cursor)
+	if err != nil {
+		return posts, err
+	}
+
+	cmp, dir := keyset(params.Desc, params.Cursor)
+	order := `path, id`
+	if params.Desc {
+		order = `path[1] DESC, path, id`
+	}
+	rows, err := p.Conn.Query(ctx, fmt.Sprintf(`SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
+		WHERE path[1] IN (SELECT id FROM post WHERE thread = $1 AND parent IS NULL AND id %s $2 ORDER BY id %s LIMIT $3)
+		ORDER BY %s;`, cmp, dir, order), threadId, id, params.Limit)
+	if err != nil {
+		return posts, database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var post models.Post
+		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message,
+			&post.Parent, &post.Thread, &post.IsDeleted)
+		if err != nil {
+			return posts, database.Translate(err)
+		}
+		posts = append(posts, post)
+	}
+	return posts, database.Translate(rows.Err())
+}
@@ -1,58 +1,63 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+	"strconv"
 	"strings"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/internal/logging"
 	domain "technopark-dbms-forum/internal/forum"
 	models "technopark-dbms-forum/models"
 	"time"
 )
 
 type postgresForumRepository struct {
-	Conn *pgx.ConnPool
+	Conn database.Conn
+	log  zerolog.Logger
 }
 
-func NewPostgresForumRepository(Conn *pgx.ConnPool) domain.ForumRepository {
-	return &postgresForumRepository{Conn: Conn}
+func NewPostgresForumRepository(Conn database.Conn, log zerolog.Logger) domain.ForumRepository {
+	return &postgresForumRepository{Conn: Conn, log: log}
 }
 
-func (p *postgresForumRepository) InsertForum(forum models.Forum) error {
-	_, err := p.Conn.Exec(	`Insert INTO forum(Slug, "user", Title) VALUES ($1, $2, $3);`,
+func (p *postgresForumRepository) InsertForum(ctx context.Context, forum models.Forum) error {
+	_, err := p.Conn.Exec(ctx, `Insert INTO forum(Slug, "user", Title) VALUES ($1, $2, $3);`,
 		forum.Slug, forum.User, forum.Title)
 	if err != nil {
-		return err
+		return database.Translate(err)
 	}
 	return nil
 }
 
-func (p *postgresForumRepository) SelectForum(forumName string) (models.Forum, error) {
+func (p *postgresForumRepository) SelectForum(ctx context.Context, forumName string) (models.Forum, error) {
 	var forum models.Forum
-	row := p.Conn.QueryRow(`Select slug, "user", title, posts, threads From forum
+	row := p.Conn.QueryRow(ctx, `Select slug, "user", title, posts, threads From forum
 				Where slug=$1 LIMIT 1`, forumName)
 	err := row.Scan(&forum.Slug, &forum.User, &forum.Title, &forum.Posts, &forum.Threads)
 	if err != nil {
-		return models.Forum{}, models.ErrNotFound
+		return models.Forum{}, database.NotFound(err, models.ForumNotFound(forumName))
 	}
-	//forum.User = p.SelectNicknameForum(forum.UserId)
 	return forum, nil
 }
 
-func (p *postgresForumRepository) SelectNicknameForum(user_id int) string {
+func (p *postgresForumRepository) SelectNicknameForum(ctx context.Context, user_id int) string {
 	var result string
-	row := p.Conn.QueryRow(`Select nickname from users where id=$1 LIMIT 1`, user_id)
+	row := p.Conn.QueryRow(ctx, `Select nickname from users where id=$1 LIMIT 1`, user_id)
 	err := row.Scan(&result)
 	if err != nil {
-		fmt.Println(err)
+		logging.For(ctx, p.log).Error().Err(err).Int("user", user_id).Msg("select nickname")
 	}
 	return result
 }
 
-func (p *postgresForumRepository) CheckForum(forum models.Forum) (models.Forum, bool) {
+func (p *postgresForumRepository) CheckForum(ctx context.Context, forum models.Forum) (models.Forum, bool) {
 	resultForum := models.Forum{
 		Posts: -1,
 	}
-	row := p.Conn.QueryRow(`Select slug, user, title, posts, threads From forum
+	row := p.Conn.QueryRow(ctx, `Select slug, user, title, posts, threads From forum
 				Where slug=$1`, forum.Slug)
 	_ = row.Scan(&resultForum.Slug, &resultForum.User, &resultForum.Title, &resultForum.Posts, &resultForum.Threads)
 	if resultForum.Posts == -1 {
@@ -61,15 +66,15 @@ func (p *postgresForumRepository) CheckForum(forum models.Forum) (models.Forum,
 	return resultForum, true
 }
 
-func (p *postgresForumRepository) SelectUsers(user models.User) ([]models.User, error) {
+func (p *postgresForumRepository) SelectUsers(ctx context.Context, user models.User) ([]models.User, error) {
 	var users []models.User
-	rows, err := p.Conn.Query(`Select Nickname, FullName, About, Email From users Where Nickname=$1 or Email=$2 LIMIT 2;`,
+	rows, err := p.Conn.Query(ctx, `Select Nickname, FullName, About, Email From users Where Nickname=$1 or Email=$2 LIMIT 2;`,
 														user.Nickname, user.Email)
-	defer rows.Close()
 	if err != nil {
-		fmt.Println(err)
-		return users, err
+		logging.For(ctx, p.log).Error().Err(err).Str("nickname", user.Nickname).Msg("select users")
+		return users, database.Translate(err)
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var userModel models.User
 		err := rows.Scan(&userModel.Nickname, &userModel.FullName, &userModel.About, &userModel.Email)
@@ -82,29 +87,30 @@ func (p *postgresForumRepository) SelectUsers(user models.User) ([]models.User,
 	return users, nil
 }
 
-func (p *postgresForumRepository) InsertUser(user models.User) error {
-	_, err := p.Conn.Exec(	`Insert INTO users(Nickname, FullName, About, Email) VALUES ($1, $2, $3, $4);`,
-		user.Nickname, user.FullName, user.About, user.Email)
+func (p *postgresForumRepository) InsertUser(ctx context.Context, user models.User) error {
+	_, err := p.Conn.Exec(ctx, `Insert INTO users(Nickname, FullName, About, Email, password_hash)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''));`,
+		user.Nickname, user.FullName, user.About, user.Email, user.PasswordHash)
 	if err != nil {
-		fmt.Println(err)
-		return err
+		logging.For(ctx, p.log).Debug().Err(err).Str("nickname", user.Nickname).Msg("insert user")
+		return database.Translate(err)
 	}
 	return nil
 }
 
-func (p *postgresForumRepository) SelectUser(user string) (models.User, error) {
+func (p *postgresForumRepository) SelectUser(ctx context.Context, user string) (models.User, error) {
 	var userModel models.User
-	row := p.Conn.QueryRow(`Select Nickname, FullName, About, Email From users Where nickname=$1 LIMIT 1;`, user)
+	row := p.Conn.QueryRow(ctx, `Select Nickname, FullName, About, Email From users Where nickname=$1 LIMIT 1;`, user)
 	err := row.Scan(&userModel.Nickname, &userModel.FullName, &userModel.About, &userModel.Email)
 	if err != nil {
-		return models.User{}, models.ErrNotFound
+		return models.User{}, database.NotFound(err, models.UserNotFound(user))
 	}
 	return userModel, nil
 }
 
-func (p *postgresForumRepository) SelectUserByEmail(user models.User) (models.User, error) {
+func (p *postgresForumRepository) SelectUserByEmail(ctx context.Context, user models.User) (models.User, error) {
 	var userModel models.User
-	row := p.Conn.QueryRow(`Select nickname, email from users Where email=$1 LIMIT 1;`, user.Email)
+	row := p.Conn.QueryRow(ctx, `Select nickname, email from users Where email=$1 LIMIT 1;`, user.Email)
 	err := row.Scan(&userModel.Nickname, &userModel.Email)
 	if err != nil {
 		return models.User{}, nil
@@ -115,112 +121,85 @@ func (p *postgresForumRepository) SelectUserByEmail(user models.User) (models.Us
 	return userModel, models.ErrConflict
 }
 
-func (p *postgresForumRepository) UpdateUserInfo(user models.User) (models.User, error) {
+func (p *postgresForumRepository) UpdateUserInfo(ctx context.Context, user models.User) (models.User, error) {
 	var err error
 	var newUser models.User
 
-	err = p.Conn.QueryRow(
-		`UPDATE users SET email=COALESCE(NULLIF($1, ''), email), 
+	err = p.Conn.QueryRow(ctx, `UPDATE users SET email=COALESCE(NULLIF($1, ''), email), 
 							  about=COALESCE(NULLIF($2, ''), about), 
-							  fullname=COALESCE(NULLIF($3, ''), fullname) WHERE nickname=$4 RETURNING *`,
+							  fullname=COALESCE(NULLIF($3, ''), fullname) WHERE nickname=$4
+							  RETURNING nickname, fullname, about, email`,
 		user.Email,
 		user.About,
 		user.FullName,
 		user.Nickname,
 	).Scan(&newUser.Nickname, &newUser.FullName, &newUser.About, &newUser.Email)
-	//if user.FullName != "" {
-	//	_, err = p.Conn.Exec(`UPDATE users SET fullname=$1 WHERE nickname=$2;`, user.FullName, user.Nickname)
-	//	if err != nil {
-	//		return models.User{}, err
-	//	}
-	//}
-	//
-	//if user.About != "" {
-	//	_, err = p.Conn.Exec(`UPDATE users SET about=$1 WHERE nickname=$2;`, user.About, user.Nickname)
-	//	if err != nil {
-	//		return models.User{}, err
-	//	}
-	//}
-	//
-	//
-	//if user.Email != "" {
-	//	_, err = p.Conn.Exec(`UPDATE users SET email=$1 WHERE nickname=$2;`, user.Email, user.Nickname)
-	//	if err != nil {
-	//		return models.User{}, err
-	//	}
-	//}
-
-	return newUser, err
-}
-
-func (p *postgresForumRepository) SelectThreadBySlug(slug string) (models.Thread, error) {
+
+	return newUser, database.NotFound(err, models.UserNotFound(user.Nickname))
+}
+
+func (p *postgresForumRepository) SelectThreadBySlug(ctx context.Context, slug string) (models.Thread, error) {
 	var thread models.Thread
-	row := p.Conn.QueryRow(`Select id, title, author, forum, message, votes, slug, created from thread
-							Where slug=$1 LIMIT 1;`, slug)
+	row := p.Conn.QueryRow(ctx, `Select id, title, author, forum, message, votes, slug, created from thread
+							Where slug=$1 AND NOT isDeleted LIMIT 1;`, slug)
 	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes,
 					&thread.Slug, &thread.Created)
 	if err != nil {
-		return models.Thread{}, models.ErrNotFound
+		return models.Thread{}, database.NotFound(err, models.ThreadNotFound(slug))
 	}
-	//thread.Author = p.SelectNicknameForum(thread.AuthorId)
 	return thread, nil
 }
 
-func (p *postgresForumRepository) InsertThread(thread models.Thread) (models.Thread,error) {
+func (p *postgresForumRepository) InsertThread(ctx context.Context, thread models.Thread) (models.Thread,error) {
 	var newThread models.Thread
-	var row *pgx.Row
+	var row pgx.Row
 
-	row = p.Conn.QueryRow(	`Insert INTO thread(Title, Author, Created, Forum, Message, slug, Votes)
-							VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *`, thread.Title, thread.Author, thread.Created,
+	row = p.Conn.QueryRow(ctx, `Insert INTO thread(Title, Author, Created, Forum, Message, slug, Votes)
+							VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, title, author, created, forum, message, slug, votes`, thread.Title, thread.Author, thread.Created,
 							thread.Forum,
 			thread.Message, thread.Slug, thread.Votes)
 
 	err := row.Scan(&newThread.Id,&newThread.Title, &newThread.Author, &newThread.Created,
 		&newThread.Forum, &newThread.Message, &newThread.Slug, &newThread.Votes)
 	if err != nil {
-		return models.Thread{},err
+		return models.Thread{}, database.Translate(err)
 	}
-	//newThread.Author = p.SelectNicknameForum(newThread.AuthorId)
 	return newThread, nil
 }
 
-func (p *postgresForumRepository) SelectThreadById(id int) (models.Thread, error) {
+func (p *postgresForumRepository) SelectThreadById(ctx context.Context, id int) (models.Thread, error) {
 	var thread models.Thread
-	row := p.Conn.QueryRow(`Select id, title, author, forum, message, votes, slug, created from thread
-							Where id=$1 LIMIT 1;`, id)
+	row := p.Conn.QueryRow(ctx, `Select id, title, author, forum, message, votes, slug, created from thread
+							Where id=$1 AND NOT isDeleted LIMIT 1;`, id)
 
 	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes,
 		&thread.Slug, &thread.Created)
 	if err != nil {
-		return models.Thread{}, models.ErrNotFound
+		return models.Thread{}, database.NotFound(err, models.ThreadNotFound(strconv.Itoa(id)))
 	}
-	//thread.Author = p.SelectNicknameForum(thread.AuthorId)
 	return thread, nil
 }
 
-func (p *postgresForumRepository) CheckParent(post models.Post) bool {
-	fmt.Printf("menya vizvali")
-	fmt.Println(post.Parent)
+func (p *postgresForumRepository) CheckParent(ctx context.Context, post models.Post) bool {
 	var id string
-	row := p.Conn.QueryRow(`Select author from post where id=$1;`, post.Parent.Int64)
+	row := p.Conn.QueryRow(ctx, `Select author from post where id=$1;`, post.Parent.Int64)
 
 	err := row.Scan(&id)
 
 	if err == pgx.ErrNoRows {
-		fmt.Printf("FALSE FLASE FALSE")
 		return false
 	}
-	fmt.Println(id)
 	if err != nil {
-		fmt.Printf("FALSE FLASE FALSE")
+		logging.For(ctx, p.log).Error().Err(err).Int64("parent", post.Parent.Int64).Msg("check parent")
 		return false
 	}
 	return true
 }
-func (p *postgresForumRepository) InsertPost(post models.Post) (models.Post, error) {
-	var row *pgx.Row
+func (p *postgresForumRepository) InsertPost(ctx context.Context, post models.Post) (models.Post, error) {
+	var row pgx.Row
 
-	row = p.Conn.QueryRow(`INSERT INTO post(author, created, forum, message, parent, thread) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;`,
+	row = p.Conn.QueryRow(ctx, `INSERT INTO post(author, created, forum, message, parent, thread) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, author, created, forum, isEdited, message, parent, thread, path;`,
 			post.Author, post.Created, post.Forum, post.Message, post.Parent, post.Thread)
 
 	var postModel models.Post
@@ -229,71 +208,178 @@ func (p *postgresForumRepository) InsertPost(post models.Post) (models.Post, err
 
 
 
-	return postModel, err
+	return postModel, database.Translate(err)
 }
 
-func (p *postgresForumRepository) StatusOfForum() models.Status {
+// StatusOfForum sums the trigger kept counters of the stats table; exact
+// counts the rows instead, which scans every table.
+func (p *postgresForumRepository) StatusOfForum(ctx context.Context, exact bool) (models.Status, error) {
+	query := `SELECT coalesce(sum(value) FILTER (WHERE name = 'users'), 0)::bigint,
+			coalesce(sum(value) FILTER (WHERE name = 'forum'), 0)::bigint,
+			coalesce(sum(value) FILTER (WHERE name = 'thread'), 0)::bigint,
+			coalesce(sum(value) FILTER (WHERE name = 'post'), 0)::bigint,
+			coalesce(sum(value) FILTER (WHERE name = 'votes'), 0)::bigint
+		FROM stats;`
+	if exact {
+		query = `SELECT (SELECT count(*) FROM users), (SELECT count(*) FROM forum),
+			(SELECT count(*) FROM thread WHERE NOT isDeleted),
+			(SELECT count(*) FROM post p JOIN thread t ON t.id = p.thread WHERE NOT p.isDeleted AND NOT t.isDeleted),
+			(SELECT count(*) FROM votes);`
+	}
+
 	var status models.Status
-	err := p.Conn.QueryRow(`SELECT COUNT(*) FROM users;`).Scan(&status.User)
+	err := p.Conn.QueryRow(ctx, query).Scan(&status.User, &status.Forum, &status.Thread, &status.Post, &status.Vote)
+	if err != nil {
+		return models.Status{}, database.Translate(err)
+	}
+	return status, nil
+}
+
+func (p *postgresForumRepository) StatusOfForums(ctx context.Context, exact bool) ([]models.ForumStatus, error) {
+	query := `SELECT slug, threads, posts FROM forum ORDER BY slug;`
+	if exact {
+		query = `SELECT f.slug,
+			(SELECT count(*) FROM thread t WHERE t.forum = f.slug AND NOT t.isDeleted),
+			(SELECT count(*) FROM post p JOIN thread t ON t.id = p.thread
+				WHERE p.forum = f.slug AND NOT p.isDeleted AND NOT t.isDeleted)
+		FROM forum f ORDER BY f.slug;`
+	}
+
+	rows, err := p.Conn.Query(ctx, query)
 	if err != nil {
-		status.User = 0
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	forums := make([]models.ForumStatus, 0)
+	for rows.Next() {
+		var forum models.ForumStatus
+		if err := rows.Scan(&forum.Slug, &forum.Threads, &forum.Posts); err != nil {
+			return nil, database.Translate(err)
+		}
+		forums = append(forums, forum)
 	}
-	err = p.Conn.QueryRow(`SELECT COUNT(*) FROM forum;`).Scan(&status.Forum)
+	return forums, database.Translate(rows.Err())
+}
+
+// clearedTables are truncated in this order. CASCADE takes along the tables
+// referencing them; stored idempotent responses would describe deleted rows.
+var clearedTables = []string{"votes", "post", "thread", "users_forum", "forum", "users", "idempotency_keys"}
+
+// ClearDB truncates everything in one transaction: either all of it is gone
+// or nothing is.
+func (p *postgresForumRepository) ClearDB(ctx context.Context) error {
+	tx, err := p.Conn.Begin(ctx)
 	if err != nil {
-		status.Forum = 0
+		return database.Translate(err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, table := range clearedTables {
+		if _, err := tx.Exec(ctx, `TRUNCATE `+table+` CASCADE;`); err != nil {
+			return p.clearFailed(ctx, table, err)
+		}
 	}
-	err = p.Conn.QueryRow(`SELECT COUNT(*) FROM thread;`).Scan(&status.Thread)
+	return database.Translate(tx.Commit(ctx))
+}
+
+// ClearForum removes a forum with its threads, posts, votes and user list in
+// one transaction. Revisions, notifications and moderators go by cascade.
+// Stored idempotent responses of requests to the forum and its threads go
+// first, while the threads still tell their ids and slugs: a replay must not
+// answer with rows that are gone. Keys of other forums stay.
+func (p *postgresForumRepository) ClearForum(ctx context.Context, slug string) (models.ClearedForum, error) {
+	tx, err := p.Conn.Begin(ctx)
 	if err != nil {
-		status.Thread = 0
+		return models.ClearedForum{}, database.Translate(err)
 	}
-	err = p.Conn.QueryRow(`SELECT COUNT(*) FROM post;`).Scan(&status.Post)
+	defer tx.Rollback(ctx)
+
+	var cleared models.ClearedForum
+	err = tx.QueryRow(ctx, `SELECT slug FROM forum WHERE slug = $1 FOR UPDATE;`, slug).Scan(&cleared.Forum)
 	if err != nil {
-		status.Post = 0
+		return models.ClearedForum{}, database.NotFound(err, models.ForumNotFound(slug))
+	}
+
+	steps := []struct {
+		table string
+		query string
+		count *int
+	}{
+		{"idempotency_keys", forumIdempotencyKeys, nil},
+		{"votes", `DELETE FROM votes WHERE thread IN (SELECT id FROM thread WHERE forum = $1);`, &cleared.Votes},
+		{"post", `DELETE FROM post WHERE thread IN (SELECT id FROM thread WHERE forum = $1);`, &cleared.Posts},
+		{"thread", `DELETE FROM thread WHERE forum = $1;`, &cleared.Threads},
+		{"users_forum", `DELETE FROM users_forum WHERE slug = $1;`, &cleared.Users},
+		{"forum", `DELETE FROM forum WHERE slug = $1;`, nil},
+	}
+	for _, step := range steps {
+		tag, err := tx.Exec(ctx, step.query, cleared.Forum)
+		if err != nil {
+			return models.ClearedForum{}, p.clearFailed(ctx, step.table, err)
+		}
+		if step.count != nil {
+			*step.count = int(tag.RowsAffected())
+		}
 	}
-	return status
+	if err := tx.Commit(ctx); err != nil {
+		return models.ClearedForum{}, database.Translate(err)
+	}
+	return cleared, nil
 }
 
-func (p *postgresForumRepository) ClearDB() error {
-	var err error
-	_, err = p.Conn.Exec(`TRUNCATE users CASCADE;`)
-	_, err = p.Conn.Exec(`TRUNCATE forum CASCADE;`)
-	_, err = p.Conn.Exec(`TRUNCATE thread CASCADE;`)
-	_, err = p.Conn.Exec(`TRUNCATE post CASCADE;`)
-	_, err = p.Conn.Exec(`TRUNCATE votes CASCADE;`)
-	return err
+// forumIdempotencyKeys matches the paths of thread_create on the forum and of
+// posts_create and thread_vote on its threads, by id or slug. $1 is cast to
+// text in both places so that its type is deduced once.
+const forumIdempotencyKeys = `DELETE FROM idempotency_keys
+	WHERE path = ('/api/forum/' || $1::text || '/create')::citext
+		OR path IN (SELECT ('/api/thread/' || ref || '/' || action)::citext
+			FROM thread t, unnest(ARRAY[t.id::text, t.slug::text]) ref, unnest(ARRAY['create', 'vote']) action
+			WHERE t.forum = $1::text::citext AND ref IS NOT NULL);`
+
+// clearFailed names the table in the error; the driver error only goes to
+// the log.
+func (p *postgresForumRepository) clearFailed(ctx context.Context, table string, err error) error {
+	logging.For(ctx, p.log).Error().Err(err).Str("table", table).Msg("clear")
+	return models.AsError(database.Translate(err)).
+		WithMessage("Can't clear table %s", table).
+		WithDetail("table", table)
 }
 
-func (p *postgresForumRepository) SelectVote(vote models.Vote) (models.Vote, error) {
+func (p *postgresForumRepository) SelectVote(ctx context.Context, vote models.Vote) (models.Vote, error) {
 	var voteResult models.Vote
-	row := p.Conn.QueryRow(`Select author, voice, thread from votes Where author=$1 and thread=$2;`, vote.Nickname, vote.Thread)
+	row := p.Conn.QueryRow(ctx, `Select author, voice, thread from votes Where author=$1 and thread=$2;`, vote.Nickname, vote.Thread)
 	err := row.Scan(&voteResult.Nickname, &voteResult.Voice, &voteResult.Thread)
 	if err != nil {
-		return models.Vote{}, models.ErrNotFound
+		return models.Vote{}, database.Translate(err)
 	}
 	return voteResult, nil
 }
 
 
-func (p *postgresForumRepository) UpdateVote(vote models.Vote) (models.Vote, error) {
-	_, err := p.Conn.Exec(`UPDATE votes SET voice=$1 WHERE author=$2 and thread=$3;`, vote.Voice, vote.Nickname, vote.Thread)
+func (p *postgresForumRepository) UpdateVote(ctx context.Context, vote models.Vote) (models.Vote, error) {
+	_, err := p.Conn.Exec(ctx, `UPDATE votes SET voice=$1 WHERE author=$2 and thread=$3;`, vote.Voice, vote.Nickname, vote.Thread)
 	if err != nil {
-		return models.Vote{}, err
+		return models.Vote{}, database.Translate(err)
 	}
 	return vote, nil
 }
 
-func (p *postgresForumRepository) InsertVote(vote models.Vote)  error {
-	_, err := p.Conn.Exec(`INSERT INTO votes(author, voice, thread) VALUES ($1, $2, $3);`, vote.Nickname,
-							vote.Voice, vote.Thread)
+func (p *postgresForumRepository) InsertVote(ctx context.Context, vote models.Vote)  error {
+	tag, err := p.Conn.Exec(ctx, `INSERT INTO votes(author, voice, thread) SELECT $1, $2, id FROM thread
+							WHERE id=$3 AND NOT isDeleted;`, vote.Nickname, vote.Voice, vote.Thread)
 	if err != nil {
-		return err
+		return database.Translate(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ThreadNotFound(strconv.Itoa(vote.Thread))
 	}
 	return nil
 }
 
-func (p *postgresForumRepository) SumVotesInThread(id int) int {
+func (p *postgresForumRepository) SumVotesInThread(ctx context.Context, id int) int {
 	var sum int
-	row := p.Conn.QueryRow(`Select SUM(voice) from votes WHERE thread=$1;`, id)
+	row := p.Conn.QueryRow(ctx, `Select SUM(voice) from votes WHERE thread=$1;`, id)
 	err := row.Scan(&sum)
 	if err != nil {
 		return 0
@@ -301,57 +387,89 @@ func (p *postgresForumRepository) SumVotesInThread(id int) int {
 	return sum
 }
 
-func (p *postgresForumRepository) UpdatePost(post models.Post, postUpdate models.PostUpdate) (models.Post, error) {
-
-		row := p.Conn.QueryRow(`UPDATE post SET message=COALESCE(NULLIF($1, ''), message),
+func (p *postgresForumRepository) UpdatePost(ctx context.Context, post models.Post, postUpdate models.PostUpdate) (models.Post, error) {
+	// The revision CTE keeps the replaced message whenever the update
+	// actually changes it.
+	row := p.Conn.QueryRow(ctx, `WITH old AS (SELECT id, message FROM post WHERE id=$2 FOR UPDATE),
+                             revision AS (INSERT INTO post_revisions(post, editor, message)
+                                 SELECT id, NULLIF($3, ''), message FROM old WHERE $1 <> '' AND message <> $1)
+                             UPDATE post SET message=COALESCE(NULLIF($1, ''), message),
                              isEdited = CASE WHEN $1 = '' OR message = $1 THEN isEdited ELSE true END
-                             WHERE id=$2 RETURNING *`, postUpdate.Message, post.ID)
-		err := row.Scan(&post.ID, &post.Author, &post.Created, &post.Forum,  &post.IsEdited,
-			&post.Message, &post.Parent, &post.Thread, &post.Path)
-		if err != nil {
-			return post, err
-		}
+                             WHERE id=$2 RETURNING id, author, created, forum, isEdited, message, parent, thread, path`,
+		postUpdate.Message, post.ID, postUpdate.Editor)
+	err := row.Scan(&post.ID, &post.Author, &post.Created, &post.Forum,  &post.IsEdited,
+		&post.Message, &post.Parent, &post.Thread, &post.Path)
+	if err != nil {
+		return post, database.NotFound(err, models.PostNotFound(post.ID))
+	}
 	return post, nil
 }
 
 
-func (p *postgresForumRepository) SelectPost(id int) (models.Post, error) {
+func (p *postgresForumRepository) SelectPost(ctx context.Context, id int) (models.Post, error) {
 	var postModel models.Post
-	row := p.Conn.QueryRow(`Select id, author, created, forum, isEdited, message, parent, thread from post Where id=$1 LIMIT 1;`, id)
+	row := p.Conn.QueryRow(ctx, `Select id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted from post Where id=$1 LIMIT 1;`, id)
 	err := row.Scan(&postModel.ID, &postModel.Author, &postModel.Created, &postModel.Forum,  &postModel.IsEdited,
-		&postModel.Message, &postModel.Parent, &postModel.Thread)
+		&postModel.Message, &postModel.Parent, &postModel.Thread, &postModel.IsDeleted)
 	if err != nil {
-		return models.Post{}, models.ErrNotFound
+		return models.Post{}, database.NotFound(err, models.PostNotFound(id))
 	}
-	//postModel.Author = p.SelectNickById(postModel.AuthorId)
 	return postModel, nil
 }
 
-func (p *postgresForumRepository) SelectThreads(slug string, params models.Parameters) ([]models.Thread, error) {
+// SelectPostRange returns the posts of thread with ids from first to last,
+// other threads may have posts in between.
+func (p *postgresForumRepository) SelectPostRange(ctx context.Context, thread int, first int, last int) ([]models.Post, error) {
+	rows, err := p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited,
+		CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
+		WHERE thread=$1 AND id BETWEEN $2 AND $3 ORDER BY id;`, thread, first, last)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message, &post.Parent,
+			&post.Thread, &post.IsDeleted)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+		posts = append(posts, post)
+	}
+	return posts, database.Translate(rows.Err())
+}
+
+func (p *postgresForumRepository) SelectThreads(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error) {
 	var threads []models.Thread
 	var err error
-	var rows *pgx.Rows
+	var rows pgx.Rows
+
+	if params.Cursor != nil {
+		return p.selectThreadsAfter(ctx, slug, params)
+	}
 
 	if params.Since != "" {
 		if params.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, message, slug, title, votes FROM thread
-		WHERE forum=$1 AND created <= $2 ORDER BY created DESC LIMIT $3;`, slug, params.Since, params.Limit)
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, message, slug, title, votes FROM thread
+		WHERE forum=$1 AND NOT isDeleted AND created <= $2 ORDER BY created DESC, id DESC LIMIT $3;`, slug, params.Since, params.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, message, slug, title, votes FROM thread
-		WHERE forum=$1 AND created >= $2 ORDER BY created ASC LIMIT $3;`, slug, params.Since, params.Limit)
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, message, slug, title, votes FROM thread
+		WHERE forum=$1 AND NOT isDeleted AND created >= $2 ORDER BY created ASC, id ASC LIMIT $3;`, slug, params.Since, params.Limit)
 		}
 	} else {
 		if params.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, message, slug, title, votes FROM thread
-		WHERE forum=$1 ORDER BY created DESC LIMIT $2;`, slug, params.Limit)
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, message, slug, title, votes FROM thread
+		WHERE forum=$1 AND NOT isDeleted ORDER BY created DESC, id DESC LIMIT $2;`, slug, params.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, message, slug, title, votes FROM thread
-		WHERE forum=$1 ORDER BY created ASC LIMIT $2;`, slug, params.Limit)
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, message, slug, title, votes FROM thread
+		WHERE forum=$1 AND NOT isDeleted ORDER BY created ASC, id ASC LIMIT $2;`, slug, params.Limit)
 		}
 	}
 
 	if err != nil {
-		return threads, err
+		return threads, database.Translate(err)
 	}
 	defer rows.Close()
 
@@ -363,47 +481,40 @@ func (p *postgresForumRepository) SelectThreads(slug string, params models.Param
 		if err != nil {
 			continue
 		}
-		//thread.Author = p.SelectNicknameForum(thread.AuthorId)
 		threads = append(threads, thread)
 	}
 	return threads, nil
 }
 
-func (p *postgresForumRepository) SelectUsersByForum(slug string, params models.Parameters) ([]models.User, error) {
+func (p *postgresForumRepository) SelectUsersByForum(ctx context.Context, slug string, params models.Parameters) ([]models.User, error) {
+	if params.Cursor != nil {
+		return p.selectUsersByForumAfter(ctx, slug, params)
+	}
+
 	var query string
+	args := []interface{}{slug, params.Limit}
 	if params.Desc {
 		if params.Since != "" {
-		//	query = fmt.Sprintf(`SELECT users.about, users.Email, users.FullName, users.Nickname FROM users
-    	//inner join users_forum uf on users.Nickname = uf.nickname
-        //WHERE uf.slug =$1 AND uf.nickname < '%s'
-        //ORDER BY users.Nickname DESC LIMIT NULLIF($2, 0)`, params.Since)
-			query = fmt.Sprintf(
-				`SELECT about, email, fullname, nickname 
-				FROM users_forum WHERE slug=$1 AND nickname < '%s' 
-				ORDER BY nickname DESC LIMIT NULLIF($2, 0)`, params.Since)
+			query = `SELECT about, email, fullname, nickname 
+				FROM users_forum WHERE slug=$1 AND nickname < $3 
+				ORDER BY nickname DESC LIMIT NULLIF($2, 0)`
+			args = append(args, params.Since)
 		} else {
-		//	query = `SELECT users.about, users.Email, users.FullName, users.Nickname FROM users
-    	//inner join users_forum uf on users.Nickname = uf.nickname
-        //WHERE uf.slug =$1
-        //ORDER BY users.Nickname DESC LIMIT NULLIF($2, 0)`
 			query = `SELECT about, email, fullname, nickname 
 				FROM users_forum WHERE slug=$1 
 				ORDER BY nickname DESC LIMIT NULLIF($2, 0)`
 		}
 	} else {
-		//query = fmt.Sprintf(`SELECT users.about, users.Email, users.FullName, users.Nickname FROM users
-    	//inner join users_forum uf on users.Nickname = uf.nickname
-        //WHERE uf.slug =$1 AND uf.nickname > '%s'
-        //ORDER BY users.Nickname LIMIT NULLIF($2, 0)`, params.Since)
-		query = fmt.Sprintf(`SELECT about, email, fullname, nickname
-			FROM users_forum WHERE slug=$1 AND nickname > '%s'
-			ORDER BY nickname LIMIT NULLIF($2, 0)`, params.Since)
+		query = `SELECT about, email, fullname, nickname
+			FROM users_forum WHERE slug=$1 AND nickname > $3
+			ORDER BY nickname LIMIT NULLIF($2, 0)`
+		args = append(args, params.Since)
 	}
 	var data []models.User
-	row, err := p.Conn.Query(query, slug, params.Limit)
+	row, err := p.Conn.Query(ctx, query, args...)
 
 	if err != nil {
-		return data, nil
+		return data, database.Translate(err)
 	}
 
 	defer func() {
@@ -419,49 +530,53 @@ func (p *postgresForumRepository) SelectUsersByForum(slug string, params models.
 		err = row.Scan(&u.About, &u.Email, &u.FullName, &u.Nickname)
 
 		if err != nil {
-			return data, err
+			return data, database.Translate(err)
 		}
 
 		data = append(data, u)
 	}
 
-	return data, err
+	return data, database.Translate(row.Err())
 }
 
 
-func (p *postgresForumRepository) PostFlatSort(id int, parameters models.Parameters) ([]models.Post, error) {
+func (p *postgresForumRepository) PostFlatSort(ctx context.Context, id int, parameters models.Parameters) ([]models.Post, error) {
 	var err error
-	var rows *pgx.Rows
+	var rows pgx.Rows
 	var posts []models.Post
 
+	if parameters.Cursor != nil {
+		return p.postsAfter(ctx, id, parameters, false)
+	}
+
 	if parameters.Since == "" {
 		if parameters.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 ORDER BY id DESC LIMIT $2;`, id, parameters.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 ORDER BY id LIMIT $2;`, id, parameters.Limit)
 		}
 	} else {
 		if parameters.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 AND id < $2 ORDER BY id DESC LIMIT $3;`, id, parameters.Since, parameters.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 AND id > $2 ORDER BY id LIMIT $3;`, id, parameters.Since, parameters.Limit)
 		}
 	}
 
 	if err != nil {
-		return posts, err
+		return posts, database.Translate(err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var post models.Post
-		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message, &post.Parent, &post.Thread)
+		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message, &post.Parent, &post.Thread, &post.IsDeleted)
 		if err != nil {
-			return posts, err
+			return posts, database.Translate(err)
 		}
 
 		posts = append(posts, post)
@@ -469,73 +584,80 @@ func (p *postgresForumRepository) PostFlatSort(id int, parameters models.Paramet
 	return posts, nil
 }
 
-func (p *postgresForumRepository) PostTreeSort(threadId int, parameters models.Parameters) ([]models.Post, error) {
+func (p *postgresForumRepository) PostTreeSort(ctx context.Context, threadId int, parameters models.Parameters) ([]models.Post, error) {
 	var err error
-	var rows *pgx.Rows
+	var rows pgx.Rows
 	var posts []models.Post
 
+	if parameters.Cursor != nil {
+		return p.postsAfter(ctx, threadId, parameters, true)
+	}
+
 	if parameters.Since == "" {
 		if parameters.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 ORDER BY path DESC, id DESC LIMIT $2;`, threadId, parameters.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 ORDER BY path ASC, id  ASC LIMIT $2;`, threadId, parameters.Limit)
 		}
 	} else {
 		if parameters.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 AND PATH < (SELECT path FROM post WHERE id = $2)
 		ORDER BY path DESC, id  DESC LIMIT $3;`, threadId, parameters.Since, parameters.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 		WHERE thread=$1 AND PATH > (SELECT path FROM post WHERE id = $2)
 		ORDER BY path ASC, id  ASC LIMIT $3;`, threadId, parameters.Since, parameters.Limit)
 		}
 	}
 
 	if err != nil {
-		return posts, err
+		return posts, database.Translate(err)
 	}
 	defer rows.Close()
 
 
 	for rows.Next() {
 		var post models.Post
-		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message, &post.Parent, &post.Thread)
+		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message, &post.Parent, &post.Thread, &post.IsDeleted)
 		if err != nil {
-			return posts, err
+			return posts, database.Translate(err)
 		}
 
-		//post.Author = p.SelectNickById(post.AuthorId)
 		posts = append(posts, post)
 	}
 	return posts, nil
 }
 
-func (p *postgresForumRepository) PostParentTreeSort(threadId int, parameters models.Parameters) ([]models.Post, error) {
+func (p *postgresForumRepository) PostParentTreeSort(ctx context.Context, threadId int, parameters models.Parameters) ([]models.Post, error) {
 	var err error
-	var rows *pgx.Rows
+	var rows pgx.Rows
 	var posts []models.Post
 
+	if parameters.Cursor != nil {
+		return p.parentTreeAfter(ctx, threadId, parameters)
+	}
+
 	if parameters.Since == "" {
 		if parameters.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 			WHERE path[1] IN (SELECT id FROM post WHERE thread = $1 AND parent IS NULL ORDER BY id DESC LIMIT $2)
 			ORDER BY path[1] DESC, path, id;`, threadId, parameters.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 			WHERE path[1] IN (SELECT id FROM post WHERE thread = $1 AND parent IS NULL ORDER BY id LIMIT $2)
 			ORDER BY path, id;`, threadId, parameters.Limit)
 		}
 	} else {
 		if parameters.Desc {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 				WHERE path[1] IN (SELECT id FROM post WHERE thread = $1 AND parent IS NULL AND PATH[1] <
 				(SELECT path[1] FROM post WHERE id = $2) ORDER BY id DESC LIMIT $3) ORDER BY path[1] DESC, path, id;`,
 				threadId, parameters.Since, parameters.Limit)
 		} else {
-			rows, err = p.Conn.Query(`SELECT id, author, created, forum, isEdited, message, parent, thread FROM post
+			rows, err = p.Conn.Query(ctx, `SELECT id, author, created, forum, isEdited, CASE WHEN isDeleted THEN '[deleted]' ELSE message END, parent, thread, isDeleted FROM post
 				WHERE path[1] IN (SELECT id FROM post WHERE thread = $1 AND parent IS NULL AND PATH[1] >
 				(SELECT path[1] FROM post WHERE id = $2) ORDER BY id ASC LIMIT $3) ORDER BY path, id;`,
 				threadId, parameters.Since, parameters.Limit)
@@ -543,7 +665,7 @@ func (p *postgresForumRepository) PostParentTreeSort(threadId int, parameters mo
 	}
 
 	if err != nil {
-		return posts, err
+		return posts, database.Translate(err)
 	}
 	defer rows.Close()
 
@@ -551,29 +673,27 @@ func (p *postgresForumRepository) PostParentTreeSort(threadId int, parameters mo
 	for rows.Next() {
 		var post models.Post
 		err = rows.Scan(&post.ID, &post.Author, &post.Created, &post.Forum, &post.IsEdited, &post.Message,
-			&post.Parent, &post.Thread)
+			&post.Parent, &post.Thread, &post.IsDeleted)
 		if err != nil {
-			return posts, err
+			return posts, database.Translate(err)
 		}
 
-		//post.Author = p.SelectNickById(post.AuthorId)
 		posts = append(posts, post)
 	}
 	return posts, nil
 }
 
-func (p *postgresForumRepository) UpdateThread(thread models.Thread) (models.Thread, error) {
-	var row *pgx.Row
-	query := `UPDATE thread SET title=COALESCE(NULLIF($1, ''), title), message=COALESCE(NULLIF($2, ''), message) WHERE %s RETURNING *`
+func (p *postgresForumRepository) UpdateThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	var row pgx.Row
+	query := `UPDATE thread SET title=COALESCE(NULLIF($1, ''), title), message=COALESCE(NULLIF($2, ''), message) WHERE %s AND NOT isDeleted
+		RETURNING id, title, author, created, forum, message, slug, votes`
 
 	if thread.Slug == "" {
 		query = fmt.Sprintf(query, `id=$3`)
-		row = p.Conn.QueryRow(query, thread.Title, thread.Message, thread.Id)
-		//row = p.Conn.QueryRow(`UPDATE thread SET title=$1, message=$2 WHERE id=$3 RETURNING *`, thread.Title, thread.Message, thread.Id)
+		row = p.Conn.QueryRow(ctx, query, thread.Title, thread.Message, thread.Id)
 	} else {
 		query = fmt.Sprintf(query, `slug=$3`)
-		row = p.Conn.QueryRow(query, thread.Title, thread.Message, thread.Slug)
-		//row = p.Conn.QueryRow(`UPDATE thread SET title=$1, message=$2 WHERE LOWER(slug)=LOWER($3) RETURNING *`, thread.Title, thread.Message, thread.Slug)
+		row = p.Conn.QueryRow(ctx, query, thread.Title, thread.Message, thread.Slug)
 	}
 
 	var newThread models.Thread
@@ -589,25 +709,77 @@ func (p *postgresForumRepository) UpdateThread(thread models.Thread) (models.Thr
 		&newThread.Votes,
 	)
 
-	//newThread.Author = p.SelectNickById(newThread.AuthorId)
-
 	if err != nil {
-		fmt.Println(err)
-		return models.Thread{}, models.ErrNotFound
+		logging.For(ctx, p.log).Debug().Err(err).Int("thread", thread.Id).Msg("update thread")
+		slugOrId := thread.Slug
+		if slugOrId == "" {
+			slugOrId = strconv.Itoa(thread.Id)
+		}
+		return models.Thread{}, database.NotFound(err, models.ThreadNotFound(slugOrId))
 	}
 
 	return newThread, nil
 }
 
-func (p *postgresForumRepository) NewTransaction() (*pgx.Tx, error) {
-	return p.Conn.Begin()
+// DeletePost and DeleteThread only flag the row; the soft delete triggers
+// keep the forum counters in step.
+func (p *postgresForumRepository) DeletePost(ctx context.Context, id int) error {
+	_, err := p.Conn.Exec(ctx, `UPDATE post SET isDeleted = TRUE WHERE id=$1 AND NOT isDeleted;`, id)
+	return database.Translate(err)
+}
+
+func (p *postgresForumRepository) DeleteThread(ctx context.Context, id int) error {
+	_, err := p.Conn.Exec(ctx, `UPDATE thread SET isDeleted = TRUE WHERE id=$1 AND NOT isDeleted;`, id)
+	return database.Translate(err)
+}
+
+// SelectRevisions lists the revisions of a post, newest first.
+func (p *postgresForumRepository) SelectRevisions(ctx context.Context, postId int) ([]models.PostRevision, error) {
+	revisions := []models.PostRevision{}
+	rows, err := p.Conn.Query(ctx, `SELECT id, post, COALESCE(editor, ''), message, created FROM post_revisions
+		WHERE post=$1 ORDER BY id DESC;`, postId)
+	if err != nil {
+		return revisions, database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision models.PostRevision
+		err = rows.Scan(&revision.ID, &revision.Post, &revision.Editor, &revision.Message, &revision.Created)
+		if err != nil {
+			return revisions, database.Translate(err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, database.Translate(rows.Err())
+}
+
+func (p *postgresForumRepository) SelectRevision(ctx context.Context, postId int, id int) (models.PostRevision, error) {
+	var revision models.PostRevision
+	err := p.Conn.QueryRow(ctx, `SELECT id, post, COALESCE(editor, ''), message, created FROM post_revisions
+		WHERE post=$1 AND id=$2;`, postId, id).
+		Scan(&revision.ID, &revision.Post, &revision.Editor, &revision.Message, &revision.Created)
+	if err != nil {
+		return revision, database.NotFound(err, models.RevisionNotFound(postId, id))
+	}
+	return revision, nil
+}
+
+func (p *postgresForumRepository) CountRevisions(ctx context.Context, postId int) (int, error) {
+	var count int
+	err := p.Conn.QueryRow(ctx, `SELECT count(*) FROM post_revisions WHERE post=$1;`, postId).Scan(&count)
+	return count, database.Translate(err)
 }
 
-func (p *postgresForumRepository) Rollback(tx *pgx.Tx) {
-	p.Rollback(tx)
+func (p *postgresForumRepository) NewTransaction(ctx context.Context) (pgx.Tx, error) {
+	return p.Conn.Begin(ctx)
 }
 
-func (p *postgresForumRepository) InsertPosts(posts *[]models.Post, thread models.Thread) (*[]models.Post, error) {
+func (p *postgresForumRepository) Rollback(ctx context.Context, tx pgx.Tx) {
+	tx.Rollback(ctx)
+}
+
+func (p *postgresForumRepository) InsertPosts(ctx context.Context, posts *[]models.Post, thread models.Thread) (*[]models.Post, error) {
 	query := `INSERT INTO post(author, created, forum, message, parent, thread) VALUES`
 
 	var values []interface{}
@@ -618,9 +790,6 @@ func (p *postgresForumRepository) InsertPosts(posts *[]models.Post, thread model
 			i * 6 + 1, i * 6 + 2, i * 6 + 3, i * 6 + 4, i * 6 + 5, i * 6 + 6,
 		)
 
-		//userId := p.SelectIdByNickname(post.Author)
-
-
 		query += value
 
 		values = append(values, post.Author)
@@ -634,147 +803,45 @@ func (p *postgresForumRepository) InsertPosts(posts *[]models.Post, thread model
 	query = strings.TrimSuffix(query, ",")
 	query += ` RETURNING id, created, forum, isEdited, thread;`
 
-	rows, err := p.Conn.Query(query, values...)
+	rows, err := p.Conn.Query(ctx, query, values...)
 	if err != nil {
-		fmt.Println("error of insert")
-		return nil, err
+		logging.For(ctx, p.log).Debug().Err(err).Int("thread", thread.Id).Int("posts", len(*posts)).Msg("insert posts")
+		return nil, database.Translate(err)
 	}
 	defer rows.Close()
-	//var postsResult []models.Post
 
 	for i, _ := range *posts {
 		if rows.Next() {
 			err := rows.Scan(&(*posts)[i].ID, &(*posts)[i].Created, &(*posts)[i].Forum, &(*posts)[i].IsEdited, &(*posts)[i].Thread)
 			if err != nil {
-				fmt.Println(err)
-				return nil, models.ErrConflict
+				logging.For(ctx, p.log).Error().Err(err).Int("thread", thread.Id).Msg("scan inserted posts")
+				return nil, database.Translate(err)
 			}
 		}
 	}
 	if rows.Err() != nil {
-
-		return nil, rows.Err()
-		//switch rows.Err().(pgx.PgError).Code {
-		//case "23503":
-		//	return nil, models.ErrNotFound
-		//default:
-		//	return nil, models.ErrConflict
-		//}
-	}
-
-	//fmt.Println(*posts)
-	//for rows.Next() {
-	//	var postModel models.Post
-	//	err := rows.Scan(&postModel.ID, &postModel.Author, &postModel.Created, &postModel.Forum,  &postModel.IsEdited,
-	//		&postModel.Message, &postModel.Parent, &postModel.Thread, &postModel.Path)
-	//	if err != nil {
-	//		fmt.Println("error of SCAN")
-	//		return nil, err
-	//	}
-	//
-	//	if !postModel.Parent.Valid {
-	//		postModel.Parent.Int64 = 0
-	//		postModel.Parent.Valid = true
-	//	}
-	//	//postModel.Author = p.SelectNicknameForum(postModel.AuthorId)
-	//	postsResult = append(postsResult, postModel)
-	//}
+		return nil, database.Translate(rows.Err())
+	}
 
 	return posts, err
-	//query := `INSERT INTO post(
-    //             author,
-    //             created,
-    //             message,
-    //             parent,
-	//			 thread,
-	//			 forum) VALUES `
-	//data := make([]models.Post, 0, 0)
-	//if len(*posts) == 0 {
-	//	return &data, nil
-	//}
-	//
-	//slug := thread.Forum
-	//
-	//
-	//timeCreated := time.Now()
-	//var valuesNames []string
-	//var values []interface{}
-	//i := 1
-	//for _, element := range *posts {
-	//	valuesNames = append(valuesNames, fmt.Sprintf(
-	//		"($%d, $%d, $%d, nullif($%d, 0), $%d, $%d)",
-	//		i, i+1, i+2, i+3, i+4, i+5))
-	//	i += 6
-	//	values = append(values, element.Author, timeCreated, element.Message, element.Parent, thread.Id, slug)
-	//}
-	//
-	//query += strings.Join(valuesNames[:], ",")
-	//query += " RETURNING *"
-	//row, err := p.Conn.Query(query, values...)
-	//
-	//if err != nil {
-	//	return &data, err
-	//}
-	//defer func() {
-	//	if row != nil {
-	//		row.Close()
-	//	}
-	//}()
-	//
-	//for row.Next() {
-	//
-	//	var post models.Post
-	//
-	//
-	//	err = row.Scan(&post.ID, &post.Author, &post.Created, &post.Forum,  &post.IsEdited,
-	//		&post.Message, &post.Parent, &post.Thread, &post.Path)
-	//
-	//	if err != nil {
-	//		return &data, err
-	//	}
-	//
-	//	data = append(data, post)
-	//
-	//}
-	//
-	//if row.Err() != nil {
-	//	//
-	//		return nil, row.Err()
-	//	//	//switch rows.Err().(pgx.PgError).Code {
-	//	//	//case "23503":
-	//	//	//	return nil, models.ErrNotFound
-	//	//	//default:
-	//	//	//	return nil, models.ErrConflict
-	//	//	//}
-	//	}
-	//
-	//return &data, err
-}
-
-func (p *postgresForumRepository) SelectNickById(userId int) string {
+}
+
+func (p *postgresForumRepository) SelectNickById(ctx context.Context, userId int) string {
 	var result string
-	row := p.Conn.QueryRow(`Select nickname from users where id=$1 LIMIT 1`, userId)
+	row := p.Conn.QueryRow(ctx, `Select nickname from users where id=$1 LIMIT 1`, userId)
 	err := row.Scan(&result)
 	if err != nil {
-		fmt.Println(err)
+		logging.For(ctx, p.log).Error().Err(err).Int("user", userId).Msg("select nickname")
 	}
 	return result
 }
 
-func (p *postgresForumRepository) SelectIdByNickname(nick string) int {
+func (p *postgresForumRepository) SelectIdByNickname(ctx context.Context, nick string) int {
 	var result int
-	row := p.Conn.QueryRow(`Select id from users where nickname=$1 LIMIT 1`, nick)
+	row := p.Conn.QueryRow(ctx, `Select id from users where nickname=$1 LIMIT 1`, nick)
 	err := row.Scan(&result)
 	if err != nil {
-		fmt.Println(err)
+		logging.For(ctx, p.log).Error().Err(err).Str("nickname", nick).Msg("select user id")
 	}
 	return result
-}
-
-func (p *postgresForumRepository) AutocommitOff() {
-	p.Conn.QueryRow(`SET autocommit TO 'off';`)
-}
-
-func (p *postgresForumRepository) AutocommitOn() {
-	p.Conn.QueryRow(`SET autocommit TO 'on';`)
 }
\ No newline at end of file
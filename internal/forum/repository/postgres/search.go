@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/models"
+)
+
+// The to_tsvector expressions must match the ones of the post_search and
+// thread_search indexes.
+const (
+	postSearchQuery = `SELECT 'post'::text AS kind, p.id, p.thread, p.forum, p.author, p.created, ''::text AS title,
+		p.message AS body, ts_rank(to_tsvector('simple', p.message), q.q) AS rank
+		FROM post p JOIN thread t ON t.id = p.thread, q
+		WHERE to_tsvector('simple', p.message) @@ q.q AND NOT p.isDeleted AND NOT t.isDeleted`
+	threadSearchQuery = `SELECT 'thread'::text AS kind, t.id, t.id AS thread, t.forum, t.author, t.created, t.title,
+		t.message AS body, ts_rank(to_tsvector('simple', t.title || ' ' || t.message), q.q) AS rank
+		FROM thread t, q
+		WHERE to_tsvector('simple', t.title || ' ' || t.message) @@ q.q AND NOT t.isDeleted`
+)
+
+// Search ranks matching posts and threads together, best first unless
+// params.Desc is off. Since is a rank, kept inclusive like created of the
+// threads list. Snippets are only built for the rows of the page, from the
+// HTML-escaped body so that the <b> around matches is the only markup in
+// them.
+func (p *postgresForumRepository) Search(ctx context.Context, search models.Search, params models.Parameters) ([]models.SearchResult, error) {
+	results := []models.SearchResult{}
+
+	args := []interface{}{search.Query}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var postFilter, threadFilter string
+	filter := func(postColumn, threadColumn, op string, value interface{}) {
+		placeholder := arg(value)
+		postFilter += fmt.Sprintf(" AND %s %s %s", postColumn, op, placeholder)
+		threadFilter += fmt.Sprintf(" AND %s %s %s", threadColumn, op, placeholder)
+	}
+	if search.Forum != "" {
+		filter("p.forum", "t.forum", "=", search.Forum)
+	}
+	if search.Author != "" {
+		filter("p.author", "t.author", "=", search.Author)
+	}
+	if search.ThreadId != 0 {
+		filter("p.thread", "t.id", "=", search.ThreadId)
+	}
+	if !search.From.IsZero() {
+		filter("p.created", "t.created", ">=", search.From)
+	}
+	if !search.To.IsZero() {
+		filter("p.created", "t.created", "<=", search.To)
+	}
+
+	var parts []string
+	if search.Type != "thread" {
+		parts = append(parts, postSearchQuery+postFilter)
+	}
+	if search.Type != "post" {
+		parts = append(parts, threadSearchQuery+threadFilter)
+	}
+
+	where, dir := "", "ASC"
+	if params.Desc {
+		dir = "DESC"
+	}
+	if params.Since != "" {
+		since, err := strconv.ParseFloat(params.Since, 32)
+		if err != nil {
+			return results, models.ErrBadRequest.WithMessage("since must be a rank: %s", params.Since)
+		}
+		cmp := ">="
+		if params.Desc {
+			cmp = "<="
+		}
+		where = fmt.Sprintf(`WHERE rank %s %s::real`, cmp, arg(float32(since)))
+	}
+	if params.Cursor != nil {
+		if len(params.Cursor.Key) != 3 {
+			return results, models.ErrInvalidCursor
+		}
+		rank, err := strconv.ParseFloat(params.Cursor.Key[0], 32)
+		if err != nil {
+			return results, models.ErrInvalidCursor
+		}
+		id, err := strconv.Atoi(params.Cursor.Key[2])
+		if err != nil {
+			return results, models.ErrInvalidCursor
+		}
+		var cmp string
+		cmp, dir = keyset(params.Desc, params.Cursor)
+		where = fmt.Sprintf(`WHERE (rank, kind, id) %s (%s::real, %s::text, %s::bigint)`,
+			cmp, arg(float32(rank)), arg(params.Cursor.Key[1]), arg(id))
+	}
+
+	rows, err := p.Conn.Query(ctx, fmt.Sprintf(`WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS q)
+		SELECT kind, id, thread, forum, author, created, title,
+			ts_headline('simple', %s, q.q, 'MaxFragments=2, MinWords=5, MaxWords=20'), rank
+		FROM (%s) r, q %s
+		ORDER BY rank %s, kind %s, id %s LIMIT NULLIF(%s, 0);`,
+		escapeHTML("body"), strings.Join(parts, " UNION ALL "), where, dir, dir, dir, arg(params.Limit)), args...)
+	if err != nil {
+		return results, database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result models.SearchResult
+		err = rows.Scan(&result.Type, &result.ID, &result.Thread, &result.Forum, &result.Author, &result.Created,
+			&result.Title, &result.Snippet, &result.Rank)
+		if err != nil {
+			return results, database.Translate(err)
+		}
+		results = append(results, result)
+	}
+
+	if params.Cursor != nil && params.Cursor.Backward {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+	}
+	return results, database.Translate(rows.Err())
+}
+
+var htmlEntities = [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"'", "&#39;"}}
+
+// escapeHTML is the SQL expression escaping column like html.EscapeString;
+// & goes first so that the other entities are left alone.
+func escapeHTML(column string) string {
+	for _, entity := range htmlEntities {
+		column = fmt.Sprintf("replace(%s, '%s', '%s')", column, strings.ReplaceAll(entity[0], "'", "''"), entity[1])
+	}
+	return column
+}
@@ -0,0 +1,190 @@
+package instrumented
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	domain "technopark-dbms-forum/internal/forum"
+	"technopark-dbms-forum/internal/tracing"
+	"technopark-dbms-forum/models"
+)
+
+var tracer = otel.Tracer("technopark-dbms-forum/internal/forum/usecase")
+
+// forumUsecase wraps every call of the wrapped usecase in a span, so that a
+// request trace shows which step its time went to.
+type forumUsecase struct {
+	next domain.ForumUseCase
+}
+
+func NewForumUsecase(next domain.ForumUseCase) domain.ForumUseCase {
+	return &forumUsecase{next: next}
+}
+
+func (u *forumUsecase) Forum(ctx context.Context, forum models.Forum) (models.Forum, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.Forum")
+	result, err := u.next.Forum(ctx, forum)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) CreateUser(ctx context.Context, registration models.Registration) ([]models.User, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.CreateUser")
+	result, err := u.next.CreateUser(ctx, registration)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) GetUser(ctx context.Context, nickname string) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.GetUser")
+	result, err := u.next.GetUser(ctx, nickname)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) ChangeUserProfile(ctx context.Context, user models.User) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.ChangeUserProfile")
+	result, err := u.next.ChangeUserProfile(ctx, user)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) ForumDetails(ctx context.Context, slug string) (models.Forum, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.ForumDetails")
+	result, err := u.next.ForumDetails(ctx, slug)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) CreatingThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.CreatingThread")
+	result, err := u.next.CreatingThread(ctx, thread)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) CreatePosts(ctx context.Context, posts *[]models.Post, thread models.Thread) (*[]models.Post, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.CreatePosts")
+	result, err := u.next.CreatePosts(ctx, posts, thread)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) ThreadDetails(ctx context.Context, slug string) (models.Thread, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.ThreadDetails")
+	result, err := u.next.ThreadDetails(ctx, slug)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) StatusDB(ctx context.Context, exact bool, perForum bool) (models.Status, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.StatusDB")
+	result, err := u.next.StatusDB(ctx, exact, perForum)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) ClearForum(ctx context.Context, slug string) (models.ClearedForum, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.ClearForum")
+	result, err := u.next.ClearForum(ctx, slug)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) ClearDB(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.ClearDB")
+	err := u.next.ClearDB(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (u *forumUsecase) MakeVote(ctx context.Context, vote models.Vote, thread models.Thread) (models.Thread, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.MakeVote")
+	result, err := u.next.MakeVote(ctx, vote, thread)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) SumVotesInThread(ctx context.Context, id int) int {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.SumVotesInThread")
+	result := u.next.SumVotesInThread(ctx, id)
+	tracing.End(span, nil)
+	return result
+}
+
+func (u *forumUsecase) UpdateMessagePost(ctx context.Context, update models.PostUpdate) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.UpdateMessagePost")
+	result, err := u.next.UpdateMessagePost(ctx, update)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) PostFullDetails(ctx context.Context, id int, related string) (models.PostFull, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.PostFullDetails")
+	result, err := u.next.PostFullDetails(ctx, id, related)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) ListThreads(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.ListThreads")
+	result, err := u.next.ListThreads(ctx, slug, params)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) GetUsersByForum(ctx context.Context, slug string, params models.Parameters) ([]models.User, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.GetUsersByForum")
+	result, err := u.next.GetUsersByForum(ctx, slug, params)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) GetPostsOfThread(ctx context.Context, threadId int, parameters models.Parameters, sort string) ([]models.Post, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.GetPostsOfThread")
+	result, err := u.next.GetPostsOfThread(ctx, threadId, parameters, sort)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) UpdateThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.UpdateThread")
+	result, err := u.next.UpdateThread(ctx, thread)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) DeletePost(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.DeletePost")
+	err := u.next.DeletePost(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+func (u *forumUsecase) DeleteThread(ctx context.Context, slugOrId string) error {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.DeleteThread")
+	err := u.next.DeleteThread(ctx, slugOrId)
+	tracing.End(span, err)
+	return err
+}
+
+func (u *forumUsecase) PostHistory(ctx context.Context, id int) ([]models.PostRevision, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.PostHistory")
+	result, err := u.next.PostHistory(ctx, id)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) RevertPost(ctx context.Context, id int, revision int) (models.Post, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.RevertPost")
+	result, err := u.next.RevertPost(ctx, id, revision)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (u *forumUsecase) Search(ctx context.Context, search models.Search, params models.Parameters) ([]models.SearchResult, error) {
+	ctx, span := tracer.Start(ctx, "ForumUsecase.Search")
+	result, err := u.next.Search(ctx, search, params)
+	tracing.End(span, err)
+	return result, err
+}
@@ -1,39 +1,41 @@
 package usecase
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx"
+	"github.com/rs/zerolog"
 	"strconv"
 	"strings"
+	"technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/logging"
 	domain "technopark-dbms-forum/internal/forum"
 	"technopark-dbms-forum/models"
 )
 
 type ForumUsecase struct {
 	forumRepo domain.ForumRepository
+	auth      auth.AuthUseCase
+	log       zerolog.Logger
 }
 
-func NewForumUsecase(forumRepo domain.ForumRepository) domain.ForumUseCase {
-	return &ForumUsecase{forumRepo: forumRepo}
+func NewForumUsecase(forumRepo domain.ForumRepository, authUseCase auth.AuthUseCase, log zerolog.Logger) domain.ForumUseCase {
+	return &ForumUsecase{forumRepo: forumRepo, auth: authUseCase, log: log}
 }
 
-func (f *ForumUsecase) Forum(forum models.Forum) (models.Forum, error) {
-	user, err := f.forumRepo.SelectUser(forum.User)
+func (f *ForumUsecase) Forum(ctx context.Context, forum models.Forum) (models.Forum, error) {
+	user, err := f.forumRepo.SelectUser(ctx, forum.User)
 	if err != nil {
 		return models.Forum{}, err
 	}
 
 	forum.User = user.Nickname
 	forum.UserId = user.ID
-	err = f.forumRepo.InsertForum(forum)
+	err = f.forumRepo.InsertForum(ctx, forum)
 	if err != nil {
-		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "23503" {
-			return models.Forum{}, models.ErrNotFound
-		}
-		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "23505" {
-			forumModel, _ := f.forumRepo.SelectForum(forum.Slug)
-			return forumModel, models.ErrConflict
+		if errors.Is(err, models.ErrConflict) {
+			forumModel, _ := f.forumRepo.SelectForum(ctx, forum.Slug)
+			return forumModel, err
 		}
 		return models.Forum{}, err
 	}
@@ -44,17 +46,25 @@ func (f *ForumUsecase) Forum(forum models.Forum) (models.Forum, error) {
 	return forum, nil
 }
 
-func (f *ForumUsecase) CreateUser(user models.User) ([]models.User, error) {
+func (f *ForumUsecase) CreateUser(ctx context.Context, registration models.Registration) ([]models.User, error) {
+	user := registration.User
 	var users []models.User
-	users, err := f.forumRepo.SelectUsers(user)
+	users, err := f.forumRepo.SelectUsers(ctx, user)
 	if err != nil {
-		fmt.Println(err)
+		logging.For(ctx, f.log).Error().Err(err).Str("nickname", user.Nickname).Msg("looking up existing users")
 	}
 	if len(users) != 0 {
 		return users, models.ErrConflict
 	}
 
-	err = f.forumRepo.InsertUser(user)
+	if registration.Password != "" {
+		user.PasswordHash, err = auth.HashPassword(registration.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = f.forumRepo.InsertUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -62,64 +72,41 @@ func (f *ForumUsecase) CreateUser(user models.User) ([]models.User, error) {
 	return users, nil
 }
 
-func (f *ForumUsecase) GetUser(nickname string) (models.User, error) {
-	return f.forumRepo.SelectUser(nickname)
+func (f *ForumUsecase) GetUser(ctx context.Context, nickname string) (models.User, error) {
+	return f.forumRepo.SelectUser(ctx, nickname)
 }
 
-func (f *ForumUsecase) ChangeUserProfile(user models.User) (models.User, error) {
-	//_, err := f.forumRepo.SelectUser(user.Nickname)
-	//if err != nil {
-	//	return models.User{}, err
-	//}
-	//
-	//_, err = f.forumRepo.SelectUserByEmail(user)
-	//if err != nil {
-	//	return models.User{}, err
-	//}
-
-	userModel, err := f.forumRepo.UpdateUserInfo(user)
+func (f *ForumUsecase) ChangeUserProfile(ctx context.Context, user models.User) (models.User, error) {
+	userModel, err := f.forumRepo.UpdateUserInfo(ctx, user)
 	if err != nil {
-		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "23505" {
-			return models.User{}, models.ErrConflict
-		}
-		return models.User{}, models.ErrNotFound
+		return models.User{}, err
 	}
 
-	//userModel, err = f.forumRepo.SelectUser(user.Nickname)
-	//if err != nil {
-	//	return models.User{}, err
-	//}
-
 	return userModel, nil
 }
 
-func (f *ForumUsecase) ForumDetails(slug string) (models.Forum, error) {
-	forum, err := f.forumRepo.SelectForum(slug)
+func (f *ForumUsecase) ForumDetails(ctx context.Context, slug string) (models.Forum, error) {
+	forum, err := f.forumRepo.SelectForum(ctx, slug)
 	if err != nil {
 		return models.Forum{}, err
 	}
 	return forum, nil
 }
 
-func (f *ForumUsecase) CreatingThread(thread models.Thread) (models.Thread, error) {
-	forum, err := f.forumRepo.SelectForum(thread.Forum)
+func (f *ForumUsecase) CreatingThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	forum, err := f.forumRepo.SelectForum(ctx, thread.Forum)
 	if err != nil {
 		return models.Thread{}, err
 	}
 
-	user, err := f.forumRepo.SelectUser(thread.Author)
+	user, err := f.forumRepo.SelectUser(ctx, thread.Author)
 	if err != nil {
 		return models.Thread{}, err
 	}
 	thread.Author = user.Nickname
 	thread.Forum = forum.Slug
 
-	if (thread.Slug != "") {
-		//threadModel, err := f.forumRepo.SelectThreadBySlug(thread.Slug)
-		//if err == nil {
-		//	return threadModel, models.ErrConflict
-		//}
-	} else {
+	if thread.Slug == "" {
 		slug, err := uuid.NewRandom()
 		if err != nil {
 			return models.Thread{}, err
@@ -128,11 +115,11 @@ func (f *ForumUsecase) CreatingThread(thread models.Thread) (models.Thread, erro
 	}
 
 	slug := thread.Slug
-	thread, err = f.forumRepo.InsertThread(thread)
+	thread, err = f.forumRepo.InsertThread(ctx, thread)
 	if err != nil {
-		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "23505" {
-			threadModel, _ := f.forumRepo.SelectThreadBySlug(slug)
-			return threadModel, models.ErrConflict
+		if errors.Is(err, models.ErrConflict) {
+			threadModel, _ := f.forumRepo.SelectThreadBySlug(ctx, slug)
+			return threadModel, err
 		}
 
 
@@ -142,32 +129,28 @@ func (f *ForumUsecase) CreatingThread(thread models.Thread) (models.Thread, erro
 	return thread, nil
 }
 
-func (f *ForumUsecase) CreatePosts(posts *[]models.Post, thread models.Thread) (*[]models.Post, error) {
+func (f *ForumUsecase) CreatePosts(ctx context.Context, posts *[]models.Post, thread models.Thread) (*[]models.Post, error) {
 	var postsCreated *[]models.Post
 
-	postsCreated, err := f.forumRepo.InsertPosts(posts, thread)
+	postsCreated, err := f.forumRepo.InsertPosts(ctx, posts, thread)
 	if err != nil {
-		fmt.Println(err)
-		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "23503" {
-						return nil, models.ErrNotFound
-		} else {
-			return nil, models.ErrConflict
-		}
+		logging.For(ctx, f.log).Debug().Err(err).Int("thread", thread.Id).Msg("creating posts")
+		return nil, err
 	}
 
 	return postsCreated, nil
 }
 
-func (f *ForumUsecase) ThreadDetails(slug string) (models.Thread, error) {
+func (f *ForumUsecase) ThreadDetails(ctx context.Context, slug string) (models.Thread, error) {
 	id, err := strconv.Atoi(slug)
 	var thread models.Thread
 	if err != nil {
-		thread, err = f.forumRepo.SelectThreadBySlug(slug)
+		thread, err = f.forumRepo.SelectThreadBySlug(ctx, slug)
 		if err != nil {
 			return models.Thread{}, err
 		}
 	} else {
-		thread, err = f.forumRepo.SelectThreadById(id)
+		thread, err = f.forumRepo.SelectThreadById(ctx, id)
 		if err != nil {
 			return models.Thread{}, err
 		}
@@ -176,14 +159,14 @@ func (f *ForumUsecase) ThreadDetails(slug string) (models.Thread, error) {
 	return thread, nil
 }
 
-func (f* ForumUsecase) ListThreads(slug string, params models.Parameters) ([]models.Thread, error) {
+func (f* ForumUsecase) ListThreads(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error) {
 
-	_, err := f.forumRepo.SelectForum(slug)
+	_, err := f.forumRepo.SelectForum(ctx, slug)
 	if err != nil {
 		return nil, err
 	}
 
-	threads, err := f.forumRepo.SelectThreads(slug, params)
+	threads, err := f.forumRepo.SelectThreads(ctx, slug, params)
 	if err != nil {
 		return nil, err
 	}
@@ -192,60 +175,77 @@ func (f* ForumUsecase) ListThreads(slug string, params models.Parameters) ([]mod
 
 }
 
-func (f* ForumUsecase) StatusDB() models.Status {
-	return f.forumRepo.StatusOfForum()
+func (f* ForumUsecase) StatusDB(ctx context.Context, exact bool, perForum bool) (models.Status, error) {
+	status, err := f.forumRepo.StatusOfForum(ctx, exact)
+	if err != nil || !perForum {
+		return status, err
+	}
+	status.Forums, err = f.forumRepo.StatusOfForums(ctx, exact)
+	if err != nil {
+		return models.Status{}, err
+	}
+	return status, nil
 }
 
-func (f *ForumUsecase) ClearDB() error {
-	return f.forumRepo.ClearDB()
+func (f *ForumUsecase) ClearDB(ctx context.Context) error {
+	return f.forumRepo.ClearDB(ctx)
 }
 
-func (f *ForumUsecase) MakeVote(vote models.Vote, thread models.Thread) (models.Thread, error) {
-	err := f.forumRepo.InsertVote(vote)
+func (f *ForumUsecase) ClearForum(ctx context.Context, slug string) (models.ClearedForum, error) {
+	return f.forumRepo.ClearForum(ctx, slug)
+}
+
+func (f *ForumUsecase) MakeVote(ctx context.Context, vote models.Vote, thread models.Thread) (models.Thread, error) {
+	err := f.forumRepo.InsertVote(ctx, vote)
 	if err != nil {
-		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "23505" {
-			_, err := f.forumRepo.UpdateVote(vote)
+		if errors.Is(err, models.ErrConflict) {
+			_, err := f.forumRepo.UpdateVote(ctx, vote)
 			if err != nil {
 				return models.Thread{}, err
 			}
 			return thread, nil
 		}
-		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "23503" {
-			return models.Thread{}, models.ErrNotFound
-		}
 		return models.Thread{}, err
 	}
 	return thread, nil
 }
 
-func (f *ForumUsecase) SumVotesInThread(id int) int {
-	return f.forumRepo.SumVotesInThread(id)
+func (f *ForumUsecase) SumVotesInThread(ctx context.Context, id int) int {
+	return f.forumRepo.SumVotesInThread(ctx, id)
 }
 
-func (f *ForumUsecase) UpdateMessagePost(update models.PostUpdate) (models.Post, error){
-	var post models.Post
-
+func (f *ForumUsecase) UpdateMessagePost(ctx context.Context, update models.PostUpdate) (models.Post, error){
+	post, err := f.forumRepo.SelectPost(ctx, update.ID)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if post.IsDeleted {
+		return models.Post{}, postDeleted(post)
+	}
+	if err := f.auth.AuthorizeEdit(ctx, post.Forum, post.Author); err != nil {
+		return models.Post{}, err
+	}
 
-	post.ID = update.ID
-	post, err := f.forumRepo.UpdatePost(post, update)
+	update.Editor, _ = auth.Nickname(ctx)
+	post, err = f.forumRepo.UpdatePost(ctx, post, update)
 	if err != nil {
-		return models.Post{}, models.ErrNotFound
+		return models.Post{}, err
 	}
 
 	return post, nil
 }
 
 
-func (f *ForumUsecase) PostFullDetails(id int, related string) (models.PostFull, error) {
+func (f *ForumUsecase) PostFullDetails(ctx context.Context, id int, related string) (models.PostFull, error) {
 	var postFull models.PostFull
-	post, err := f.forumRepo.SelectPost(id)
+	post, err := f.forumRepo.SelectPost(ctx, id)
 	if err != nil {
 		return models.PostFull{}, err
 	}
 	postFull.Post = &post
 
 	if strings.Contains(related, "user") {
-		author, err := f.forumRepo.SelectUser(post.Author)
+		author, err := f.forumRepo.SelectUser(ctx, post.Author)
 		if err != nil {
 			return models.PostFull{}, err
 		}
@@ -253,7 +253,7 @@ func (f *ForumUsecase) PostFullDetails(id int, related string) (models.PostFull,
 	}
 
 	if strings.Contains(related, "thread") {
-		thread, err := f.forumRepo.SelectThreadById(post.Thread)
+		thread, err := f.forumRepo.SelectThreadById(ctx, post.Thread)
 		if err != nil {
 			return models.PostFull{}, err
 		}
@@ -267,24 +267,32 @@ func (f *ForumUsecase) PostFullDetails(id int, related string) (models.PostFull,
 	}
 
 	if strings.Contains(related, "forum") {
-		forum, err := f.forumRepo.SelectForum(post.Forum)
+		forum, err := f.forumRepo.SelectForum(ctx, post.Forum)
 		if err != nil {
 			return models.PostFull{}, err
 		}
 		postFull.Forum = &forum
 	}
 
+	if strings.Contains(related, "revisions") {
+		count, err := f.forumRepo.CountRevisions(ctx, post.ID)
+		if err != nil {
+			return models.PostFull{}, err
+		}
+		postFull.Revisions = &count
+	}
+
 	return postFull, nil
 }
 
 
-func (f *ForumUsecase) GetUsersByForum(slug string, params models.Parameters) ([]models.User, error) {
-	_, err := f.forumRepo.SelectForum(slug)
+func (f *ForumUsecase) GetUsersByForum(ctx context.Context, slug string, params models.Parameters) ([]models.User, error) {
+	_, err := f.forumRepo.SelectForum(ctx, slug)
 	if err != nil {
 		return nil, err
 	}
 
-	users, err := f.forumRepo.SelectUsersByForum(slug, params)
+	users, err := f.forumRepo.SelectUsersByForum(ctx, slug, params)
 	if err != nil {
 		return nil, err
 	}
@@ -292,40 +300,115 @@ func (f *ForumUsecase) GetUsersByForum(slug string, params models.Parameters) ([
 	return users, nil
 }
 
-func (f *ForumUsecase) GetPostsOfThread(threadId int, parameters models.Parameters, sort string) ([]models.Post, error) {
+func (f *ForumUsecase) GetPostsOfThread(ctx context.Context, threadId int, parameters models.Parameters, sort string) ([]models.Post, error) {
 	switch sort {
 	case "flat":
-		return f.forumRepo.PostFlatSort(threadId, parameters)
+		return f.forumRepo.PostFlatSort(ctx, threadId, parameters)
 	case "tree":
-		return f.forumRepo.PostTreeSort(threadId, parameters)
+		return f.forumRepo.PostTreeSort(ctx, threadId, parameters)
 	case "parent_tree":
-		return f.forumRepo.PostParentTreeSort(threadId, parameters)
+		return f.forumRepo.PostParentTreeSort(ctx, threadId, parameters)
 	default:
-		return f.forumRepo.PostFlatSort(threadId, parameters)
+		return f.forumRepo.PostFlatSort(ctx, threadId, parameters)
 	}
 }
 
-func (f *ForumUsecase) UpdateThread(thread models.Thread) (models.Thread, error) {
-	//var oldThread models.Thread
-	//var err error
-	//if thread.Slug == "" {
-	//	oldThread, err = f.forumRepo.SelectThreadById(thread.Id)
-	//	if err != nil {
-	//		return models.Thread{}, err
-	//	}
-	//} else {
-	//	oldThread, err = f.forumRepo.SelectThreadBySlug(thread.Slug)
-	//	if err != nil {
-	//		return models.Thread{}, err
-	//	}
-	//}
-	//
-	//if thread.Title == "" {
-	//	thread.Title = oldThread.Title
-	//}
-	//if thread.Message == "" {
-	//	thread.Message = oldThread.Message
-	//}
-
-	return f.forumRepo.UpdateThread(thread)
-}
\ No newline at end of file
+func (f *ForumUsecase) UpdateThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	var existing models.Thread
+	var err error
+	if thread.Slug == "" {
+		existing, err = f.forumRepo.SelectThreadById(ctx, thread.Id)
+	} else {
+		existing, err = f.forumRepo.SelectThreadBySlug(ctx, thread.Slug)
+	}
+	if err != nil {
+		return models.Thread{}, err
+	}
+	if err := f.auth.AuthorizeEdit(ctx, existing.Forum, existing.Author); err != nil {
+		return models.Thread{}, err
+	}
+
+	return f.forumRepo.UpdateThread(ctx, thread)
+}
+
+func (f *ForumUsecase) DeleteThread(ctx context.Context, slugOrId string) error {
+	thread, err := f.ThreadDetails(ctx, slugOrId)
+	if err != nil {
+		return err
+	}
+	if err := f.auth.AuthorizeEdit(ctx, thread.Forum, thread.Author); err != nil {
+		return err
+	}
+	return f.forumRepo.DeleteThread(ctx, thread.Id)
+}
+
+// DeletePost leaves a tombstone: replies keep their place under it.
+func (f *ForumUsecase) DeletePost(ctx context.Context, id int) error {
+	post, err := f.forumRepo.SelectPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	if post.IsDeleted {
+		return nil
+	}
+	if err := f.auth.AuthorizeEdit(ctx, post.Forum, post.Author); err != nil {
+		return err
+	}
+	return f.forumRepo.DeletePost(ctx, id)
+}
+
+// PostHistory hides the revisions of deleted posts along with their message.
+func (f *ForumUsecase) PostHistory(ctx context.Context, id int) ([]models.PostRevision, error) {
+	post, err := f.forumRepo.SelectPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.IsDeleted {
+		return nil, postDeleted(post)
+	}
+	return f.forumRepo.SelectRevisions(ctx, id)
+}
+
+// RevertPost is an edit back to an older message, so it is recorded as a
+// revision itself and can be undone the same way.
+func (f *ForumUsecase) RevertPost(ctx context.Context, id int, revision int) (models.Post, error) {
+	post, err := f.forumRepo.SelectPost(ctx, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if post.IsDeleted {
+		return models.Post{}, postDeleted(post)
+	}
+	if err := f.auth.AuthorizeModerate(ctx, post.Forum); err != nil {
+		return models.Post{}, err
+	}
+
+	old, err := f.forumRepo.SelectRevision(ctx, id, revision)
+	if err != nil {
+		return models.Post{}, err
+	}
+	update := models.PostUpdate{ID: id, Message: old.Message}
+	update.Editor, _ = auth.Nickname(ctx)
+	return f.forumRepo.UpdatePost(ctx, post, update)
+}
+
+func (f *ForumUsecase) Search(ctx context.Context, search models.Search, params models.Parameters) ([]models.SearchResult, error) {
+	if search.Forum != "" {
+		if _, err := f.forumRepo.SelectForum(ctx, search.Forum); err != nil {
+			return nil, err
+		}
+	}
+	if search.Thread != "" {
+		thread, err := f.ThreadDetails(ctx, search.Thread)
+		if err != nil {
+			return nil, err
+		}
+		search.ThreadId = thread.Id
+	}
+	return f.forumRepo.Search(ctx, search, params)
+}
+
+func postDeleted(post models.Post) error {
+	return models.ErrConflict.WithMessage("Post %d is deleted", post.ID).
+		WithDetail("id", strconv.Itoa(post.ID))
+}
@@ -0,0 +1,154 @@
+// Package health answers liveness and readiness probes of container
+// orchestrators and reports diagnostics for operators.
+package health
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/internal/migrations"
+)
+
+// checkTimeout keeps a probe answering even when the database hangs.
+const checkTimeout = 2 * time.Second
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+type DatabaseCheck struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	// AcquireMs is the wait for a pool connection, PingMs the round trip.
+	AcquireMs float64 `json:"acquireMs"`
+	PingMs    float64 `json:"pingMs"`
+}
+
+type SchemaCheck struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	migrations.Schema
+}
+
+type Report struct {
+	Status   string        `json:"status"`
+	Database DatabaseCheck `json:"database"`
+	Schema   SchemaCheck   `json:"schema"`
+}
+
+type Diagnostics struct {
+	Report
+	StartedAt     time.Time          `json:"startedAt"`
+	UptimeSeconds int64              `json:"uptimeSeconds"`
+	GoVersion     string             `json:"goVersion"`
+	Goroutines    int                `json:"goroutines"`
+	Pool          database.PoolStats `json:"pool"`
+}
+
+type Checker struct {
+	pool     *pgxpool.Pool
+	migrator *migrations.Migrator
+	started  time.Time
+}
+
+func NewChecker(pool *pgxpool.Pool, migrator *migrations.Migrator) *Checker {
+	return &Checker{pool: pool, migrator: migrator, started: time.Now()}
+}
+
+// Check runs the readiness checks: a pool connection can be acquired and
+// pinged, and every embedded migration is applied. A schema ahead of this
+// build is fine, as during a rolling update.
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	report := Report{
+		Status:   statusOK,
+		Database: c.checkDatabase(ctx),
+		Schema:   c.checkSchema(ctx),
+	}
+	if !report.Database.Healthy || !report.Schema.Healthy {
+		report.Status = statusUnavailable
+	}
+	return report
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) DatabaseCheck {
+	start := time.Now()
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return DatabaseCheck{Error: err.Error()}
+	}
+	defer conn.Release()
+	check := DatabaseCheck{Healthy: true, AcquireMs: milliseconds(time.Since(start))}
+
+	start = time.Now()
+	if err := conn.Conn().Ping(ctx); err != nil {
+		return DatabaseCheck{Error: err.Error(), AcquireMs: check.AcquireMs}
+	}
+	check.PingMs = milliseconds(time.Since(start))
+	return check
+}
+
+func (c *Checker) checkSchema(ctx context.Context) SchemaCheck {
+	schema, err := c.migrator.Schema(ctx)
+	if err != nil {
+		return SchemaCheck{Error: err.Error(), Schema: migrations.Schema{Latest: c.migrator.Latest(), Pending: []int{}}}
+	}
+	check := SchemaCheck{Healthy: len(schema.Pending) == 0, Schema: schema}
+	if !check.Healthy {
+		check.Error = "migrations are pending"
+	}
+	return check
+}
+
+// Liveness only tells the process serves HTTP: restarting it would not fix
+// a database outage.
+func (c *Checker) Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		httpio.WriteJSON(w, http.StatusOK, map[string]string{"status": statusOK})
+	}
+}
+
+// Readiness answers 503 while the instance should get no traffic.
+func (c *Checker) Readiness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		report := c.Check(r.Context())
+		httpio.WriteJSON(w, status(report), report)
+	}
+}
+
+func (c *Checker) Diagnostics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		report := c.Check(r.Context())
+		diagnostics := Diagnostics{
+			Report:        report,
+			StartedAt:     c.started,
+			UptimeSeconds: int64(time.Since(c.started).Seconds()),
+			GoVersion:     runtime.Version(),
+			Goroutines:    runtime.NumGoroutine(),
+			Pool:          database.Stats(r.Context(), c.pool),
+		}
+		httpio.WriteJSON(w, status(report), diagnostics)
+	}
+}
+
+func status(report Report) int {
+	if report.Status != statusOK {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
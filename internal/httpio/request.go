@@ -0,0 +1,77 @@
+package httpio
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"technopark-dbms-forum/internal/validation"
+	"technopark-dbms-forum/models"
+)
+
+// bodyTooLarge is what http.MaxBytesReader fails with once BodyLimitMiddleware's
+// limit is exceeded.
+const bodyTooLarge = "http: request body too large"
+
+func DecodeJSON(r *http.Request, value interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(value)
+	switch {
+	case err == nil:
+		return nil
+	case err.Error() == bodyTooLarge:
+		return models.ErrPayloadTooLarge
+	case err == io.EOF:
+		return models.ErrBadRequest.WithMessage("Request body is empty")
+	default:
+		return models.ErrBadRequest.WithMessage("Malformed JSON: %v", err)
+	}
+}
+
+// ReadBody reads the whole body, reporting BodyLimitMiddleware's overflow
+// as 413 like DecodeJSON does.
+func ReadBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	switch {
+	case err == nil:
+		return body, nil
+	case err.Error() == bodyTooLarge:
+		return nil, models.ErrPayloadTooLarge
+	default:
+		return nil, models.ErrBadRequest.WithMessage("Can't read request body: %v", err)
+	}
+}
+
+func Validate(value interface{}) error {
+	if fields := validation.Struct(value); len(fields) != 0 {
+		return models.ErrValidation.WithFields(fields)
+	}
+	return nil
+}
+
+func ValidatePartial(value interface{}) error {
+	if fields := validation.Partial(value); len(fields) != 0 {
+		return models.ErrValidation.WithFields(fields)
+	}
+	return nil
+}
+
+// ClientIP is the address of the client. With trustProxy it is the last
+// X-Forwarded-For entry, the one the proxy in front added: the entries
+// before it come from the client and may be anything.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) != 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(entries[len(entries)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
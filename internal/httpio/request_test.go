@@ -0,0 +1,34 @@
+package httpio
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		forwarded  []string
+		want       string
+	}{
+		{"direct", false, nil, "10.0.0.1"},
+		{"forwarded but not trusted", false, []string{"1.1.1.1"}, "10.0.0.1"},
+		{"added by the proxy", true, []string{"1.1.1.1"}, "1.1.1.1"},
+		{"spoofed by the client", true, []string{"6.6.6.6, 1.1.1.1"}, "1.1.1.1"},
+		{"spoofed in another header", true, []string{"6.6.6.6", "1.1.1.1"}, "1.1.1.1"},
+		{"trusted without header", true, nil, "10.0.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/", nil)
+			request.RemoteAddr = "10.0.0.1:1234"
+			for _, value := range test.forwarded {
+				request.Header.Add("X-Forwarded-For", value)
+			}
+			if got := ClientIP(request, test.trustProxy); got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package httpio
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"technopark-dbms-forum/models"
+)
+
+// ErrorRecorder is a response writer that logs the internal error behind a
+// response along with its request.
+type ErrorRecorder interface {
+	RecordError(err error)
+}
+
+func WriteJSON(w http.ResponseWriter, status int, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// WriteError renders any error as a models.Error. Errors without a domain
+// meaning are logged and reported as a generic 500.
+func WriteError(w http.ResponseWriter, err error) {
+	domainErr := models.AsError(err)
+	if domainErr == models.ErrInternalServerError && !recordError(w, err) {
+		log.Error().Err(err).Msg("internal error")
+	}
+
+	body, marshalErr := json.Marshal(domainErr)
+	if marshalErr != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(domainErr.Status)
+	w.Write(body)
+}
+
+func WriteList(w http.ResponseWriter, length int, value interface{}) {
+	if length == 0 {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[]"))
+		return
+	}
+	WriteJSON(w, http.StatusOK, value)
+}
+
+// recordError hands err to the first ErrorRecorder among the writers
+// wrapping each other through Unwrap.
+func recordError(w http.ResponseWriter, err error) bool {
+	for {
+		if recorder, ok := w.(ErrorRecorder); ok {
+			recorder.RecordError(err)
+			return true
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = wrapper.Unwrap()
+	}
+}
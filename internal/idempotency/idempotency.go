@@ -0,0 +1,154 @@
+// Package idempotency answers retried requests carrying an Idempotency-Key
+// with the response of the first attempt instead of running them again.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/models"
+)
+
+const (
+	keyHeader      = "Idempotency-Key"
+	replayedHeader = "Idempotent-Replayed"
+	maxKeyLength   = 255
+	// storeTimeout bounds saving a response after the request context may
+	// already be gone.
+	storeTimeout = 5 * time.Second
+)
+
+// Response is what a key is answered with. Status 0 marks a request that is
+// still running.
+type Response struct {
+	Fingerprint []byte
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+type Store interface {
+	// Begin claims key for a new request to path. When the key is taken it
+	// returns false and the stored response.
+	Begin(ctx context.Context, scope string, key string, path string, fingerprint []byte, ttl time.Duration) (bool, Response, error)
+	Complete(ctx context.Context, scope string, key string, response Response) error
+	// Abandon releases key so that a retry runs the request again.
+	Abandon(ctx context.Context, scope string, key string) error
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware honours Idempotency-Key on the named routes. Keys are scoped by
+// the logged in user, anonymous ones by the client address as
+// httpio.ClientIP sees it with trustProxy. A response is kept for ttl unless
+// it is a server error: those may not have changed anything and the retry
+// runs again.
+func Middleware(store Store, ttl time.Duration, trustProxy bool, routes ...string) mux.MiddlewareFunc {
+	keyed := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		keyed[route] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(keyHeader)
+			route := mux.CurrentRoute(r)
+			if key == "" || route == nil || !keyed[route.GetName()] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if len(key) > maxKeyLength {
+				httpio.WriteError(w, models.ErrBadRequest.WithMessage("%s is longer than %d characters", keyHeader, maxKeyLength))
+				return
+			}
+			body, err := httpio.ReadBody(r)
+			if err != nil {
+				httpio.WriteError(w, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope, ok := auth.Nickname(r.Context())
+			if !ok {
+				// Nicknames have no colon, so the scopes cannot clash.
+				scope = "ip:" + httpio.ClientIP(r, trustProxy)
+			}
+			fingerprint := sha256.New()
+			io.WriteString(fingerprint, r.Method+" "+r.URL.Path+"\n")
+			fingerprint.Write(body)
+			sum := fingerprint.Sum(nil)
+
+			started, stored, err := store.Begin(r.Context(), scope, key, r.URL.Path, sum, ttl)
+			if err != nil {
+				httpio.WriteError(w, err)
+				return
+			}
+			if !started {
+				replay(w, stored, sum)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+				defer cancel()
+				if !completed {
+					store.Abandon(ctx, scope, key)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+			defer cancel()
+			response := Response{Status: rec.status, ContentType: w.Header().Get("Content-Type"), Body: rec.body.Bytes()}
+			completed = store.Complete(ctx, scope, key, response) == nil
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, stored Response, fingerprint []byte) {
+	if !bytes.Equal(stored.Fingerprint, fingerprint) {
+		httpio.WriteError(w, models.ErrKeyReused)
+		return
+	}
+	if stored.Status == 0 {
+		httpio.WriteError(w, models.ErrConflict.WithMessage("A request with this %s is still in progress", keyHeader))
+		return
+	}
+
+	w.Header().Set("Content-Type", stored.ContentType)
+	w.Header().Set(replayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
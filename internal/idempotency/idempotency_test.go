@@ -0,0 +1,64 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// memoryStore keeps keys forever.
+type memoryStore map[string]Response
+
+func (m memoryStore) Begin(ctx context.Context, scope string, key string, path string, fingerprint []byte, ttl time.Duration) (bool, Response, error) {
+	if stored, ok := m[scope+"/"+key]; ok {
+		return false, stored, nil
+	}
+	m[scope+"/"+key] = Response{Fingerprint: fingerprint}
+	return true, Response{}, nil
+}
+
+func (m memoryStore) Complete(ctx context.Context, scope string, key string, response Response) error {
+	response.Fingerprint = m[scope+"/"+key].Fingerprint
+	m[scope+"/"+key] = response
+	return nil
+}
+
+func (m memoryStore) Abandon(ctx context.Context, scope string, key string) error {
+	delete(m, scope+"/"+key)
+	return nil
+}
+
+func TestAnonymousKeysAreScopedByClient(t *testing.T) {
+	runs := 0
+	router := mux.NewRouter()
+	router.Use(Middleware(memoryStore{}, time.Hour, false, "vote"))
+	router.HandleFunc("/vote", func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(http.StatusCreated)
+	}).Name("vote")
+
+	send := func(remote string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/vote", strings.NewReader(`{"voice": 1}`))
+		request.RemoteAddr = remote
+		request.Header.Set(keyHeader, "same")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	send("10.0.0.1:1000")
+	if replayed := send("10.0.0.1:2000"); replayed.Header().Get(replayedHeader) != "true" || replayed.Code != http.StatusCreated {
+		t.Fatalf("retry from the same address was not replayed: %d", replayed.Code)
+	}
+	if other := send("10.0.0.2:1000"); other.Header().Get(replayedHeader) != "" {
+		t.Fatal("another client got the stored response")
+	}
+	if runs != 2 {
+		t.Fatalf("handler ran %d times, want 2", runs)
+	}
+}
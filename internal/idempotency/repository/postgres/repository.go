@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/internal/idempotency"
+	"technopark-dbms-forum/models"
+)
+
+type postgresIdempotencyStore struct {
+	Conn database.Conn
+
+	mu    sync.Mutex
+	swept time.Time
+}
+
+func NewPostgresIdempotencyStore(Conn database.Conn) idempotency.Store {
+	return &postgresIdempotencyStore{Conn: Conn, swept: time.Now()}
+}
+
+// Begin takes over an expired key that was not swept yet. path lets
+// clearing a forum find the keys of its routes.
+func (p *postgresIdempotencyStore) Begin(ctx context.Context, scope string, key string, path string, fingerprint []byte, ttl time.Duration) (bool, idempotency.Response, error) {
+	var stored idempotency.Response
+	if err := p.sweep(ctx); err != nil {
+		return false, stored, err
+	}
+
+	tag, err := p.Conn.Exec(ctx, `INSERT INTO idempotency_keys AS k (scope, key, path, fingerprint, expires)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (scope, key) DO UPDATE SET
+			path = excluded.path, fingerprint = excluded.fingerprint, status = 0, content_type = '', body = NULL,
+			created = now(), expires = excluded.expires
+		WHERE k.expires <= now();`,
+		scope, key, path, fingerprint, time.Now().Add(ttl))
+	if err != nil {
+		return false, stored, database.Translate(err)
+	}
+	if tag.RowsAffected() == 1 {
+		return true, stored, nil
+	}
+
+	err = p.Conn.QueryRow(ctx, `SELECT fingerprint, status, content_type, COALESCE(body, '') FROM idempotency_keys
+		WHERE scope=$1 AND key=$2;`, scope, key).
+		Scan(&stored.Fingerprint, &stored.Status, &stored.ContentType, &stored.Body)
+	if err != nil {
+		// The key expired or was abandoned in between.
+		return false, stored, database.NotFound(err, models.ErrConflict.WithMessage("Idempotency key was just released, retry the request"))
+	}
+	return false, stored, nil
+}
+
+func (p *postgresIdempotencyStore) Complete(ctx context.Context, scope string, key string, response idempotency.Response) error {
+	_, err := p.Conn.Exec(ctx, `UPDATE idempotency_keys SET status=$3, content_type=$4, body=$5
+		WHERE scope=$1 AND key=$2;`, scope, key, response.Status, response.ContentType, response.Body)
+	return database.Translate(err)
+}
+
+func (p *postgresIdempotencyStore) Abandon(ctx context.Context, scope string, key string) error {
+	_, err := p.Conn.Exec(ctx, `DELETE FROM idempotency_keys WHERE scope=$1 AND key=$2;`, scope, key)
+	return database.Translate(err)
+}
+
+// sweep drops expired keys about once a minute per instance, Begin does not
+// need them gone.
+func (p *postgresIdempotencyStore) sweep(ctx context.Context) error {
+	p.mu.Lock()
+	if time.Since(p.swept) < time.Minute {
+		p.mu.Unlock()
+		return nil
+	}
+	p.swept = time.Now()
+	p.mu.Unlock()
+
+	_, err := p.Conn.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires <= now();`)
+	return database.Translate(err)
+}
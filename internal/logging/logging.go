@@ -0,0 +1,120 @@
+// Package logging builds the JSON logger and ties log lines to the request
+// they were written for.
+package logging
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"technopark-dbms-forum/configs"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestID bounds IDs taken from clients so they cannot bloat the logs.
+const maxRequestID = 128
+
+type requestIDKey struct{}
+
+func New(cfg configs.LogConfig) (zerolog.Logger, error) {
+	level, err := zerolog.ParseLevel(cfg.Level)
+	if err != nil {
+		return zerolog.Logger{}, err
+	}
+	return zerolog.New(os.Stdout).Level(level).With().Timestamp().Logger(), nil
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// For tags log with the request ID and the trace carried by ctx, if any.
+func For(ctx context.Context, log zerolog.Logger) *zerolog.Logger {
+	if id := RequestID(ctx); id != "" {
+		log = log.With().Str("request_id", id).Logger()
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		log = log.With().Str("trace_id", span.TraceID().String()).Logger()
+	}
+	return &log
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	err    error
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += n
+	return n, err
+}
+
+func (r *recorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// RecordError keeps the error behind a 500 for the access line, see
+// httpio.WriteError.
+func (r *recorder) RecordError(err error) {
+	r.err = err
+}
+
+// Middleware gives every request an ID, keeping the X-Request-ID a proxy or
+// client sent, and echoes it in the response. It wraps the whole router so
+// that unmatched requests get a line too. Requests that failed with an
+// internal error are logged even with access off.
+func Middleware(log zerolog.Logger, access bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > maxRequestID {
+				id = uuid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			r = r.WithContext(WithRequestID(r.Context(), id))
+
+			start := time.Now()
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if !access && rec.err == nil {
+				return
+			}
+			level := zerolog.InfoLevel
+			if rec.status >= http.StatusInternalServerError {
+				level = zerolog.ErrorLevel
+			}
+			event := For(r.Context(), log).WithLevel(level).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Int("status", rec.status).
+				Int("bytes", rec.bytes).
+				Dur("duration_ms", time.Since(start)).
+				Str("remote", r.RemoteAddr)
+			if rec.err != nil {
+				event = event.Err(rec.err)
+			}
+			event.Msg("request")
+		})
+	}
+}
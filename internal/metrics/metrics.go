@@ -0,0 +1,137 @@
+// Package metrics exposes Prometheus metrics of HTTP routes, repository
+// calls, the connection pool and forum activity.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"technopark-dbms-forum/models"
+)
+
+const namespace = "forum"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	queryErrors     *prometheus.CounterVec
+
+	PostsCreated   prometheus.Counter
+	ThreadsCreated prometheus.Counter
+	VotesCreated   prometheus.Counter
+}
+
+func New(pool *pgxpool.Pool) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "http_requests_total",
+			Help: "HTTP requests by route name, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "http_request_duration_seconds",
+			Help:    "HTTP request latency by route name and method. Event streams are left out.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "repository_duration_seconds",
+			Help:    "Repository call latency by repository and method.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"repository", "method"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "repository_errors_total",
+			Help: "Failed repository calls by repository, method and error code.",
+		}, []string{"repository", "method", "code"}),
+		PostsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "posts_created_total", Help: "Posts created.",
+		}),
+		ThreadsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "threads_created_total", Help: "Threads created.",
+		}),
+		VotesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "votes_created_total", Help: "First votes of a user in a thread; changed votes are not counted.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newPoolCollector(pool),
+		m.requests, m.requestDuration, m.queryDuration, m.queryErrors,
+		m.PostsCreated, m.ThreadsCreated, m.VotesCreated,
+	)
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Query starts timing a repository call; the returned func records its
+// outcome.
+func (m *Metrics) Query(repository string, method string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		m.queryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.queryErrors.WithLabelValues(repository, method, models.AsError(err).Code).Inc()
+		}
+	}
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	streamed bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) Flush() {
+	flusher, ok := r.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	r.streamed = true
+	flusher.Flush()
+}
+
+// Middleware counts and times requests by route name, keeping the label
+// set bounded whatever paths clients send.
+func (m *Metrics) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			name := route.GetName()
+			m.requests.WithLabelValues(name, r.Method, strconv.Itoa(rec.status)).Inc()
+			if !rec.streamed {
+				m.requestDuration.WithLabelValues(name, r.Method).Observe(time.Since(start).Seconds())
+			}
+		})
+	}
+}
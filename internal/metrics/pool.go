@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads pgxpool statistics at scrape time.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	maxConns          *prometheus.Desc
+	totalConns        *prometheus.Desc
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	acquires          *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	acquireSeconds    *prometheus.Desc
+	newConns          *prometheus.Desc
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		pool:              pool,
+		maxConns:          desc("max_conns", "Maximum size of the pool."),
+		totalConns:        desc("total_conns", "Connections in the pool."),
+		acquiredConns:     desc("acquired_conns", "Connections in use."),
+		idleConns:         desc("idle_conns", "Idle connections."),
+		constructingConns: desc("constructing_conns", "Connections being established."),
+		acquires:          desc("acquires_total", "Successful acquires."),
+		emptyAcquires:     desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquires:  desc("canceled_acquires_total", "Acquires canceled by their context."),
+		acquireSeconds:    desc("acquire_seconds_total", "Time spent in successful acquires."),
+		newConns:          desc("new_conns_total", "Connections opened."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.maxConns, c.totalConns, c.acquiredConns, c.idleConns, c.constructingConns,
+		c.acquires, c.emptyAcquires, c.canceledAcquires, c.acquireSeconds, c.newConns,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.acquireSeconds, stat.AcquireDuration().Seconds())
+	counter(c.newConns, float64(stat.NewConnsCount()))
+}
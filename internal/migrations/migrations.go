@@ -0,0 +1,306 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// lockKey serialises migrators of several instances started against one database.
+const lockKey = 7_263_001
+
+// undefinedTable is what reading schema_migrations fails with before the
+// first migration run.
+const undefinedTable = "42P01"
+
+//go:embed sql/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Schema is how far the database is from the embedded migrations.
+type Schema struct {
+	// Version is the highest applied version.
+	Version int   `json:"version"`
+	Latest  int   `json:"latest"`
+	Pending []int `json:"pending"`
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+// All returns the embedded migrations ordered by version. File names follow
+// the NNNN_name.up.sql / NNNN_name.down.sql pattern.
+func All() ([]Migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: unknown direction", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name", name)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: bad version: %v", name, err)
+		}
+
+		body, err := files.ReadFile(path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, parts[1])
+		}
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d: missing up script", migration.Version)
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+func New(pool *pgxpool.Pool) (*Migrator, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: all}, nil
+}
+
+// Latest is the version the embedded migrations bring the schema to.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration, each in its own transaction.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `INSERT INTO schema_migrations(version, name) VALUES ($1, $2);`,
+					migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s up: %v", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+			}
+			err := inTx(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version=$1;`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s down: %v", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := done[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Schema reads the applied versions without the migration lock, so that it
+// is cheap enough for readiness probes and never waits for a running
+// migration.
+func (m *Migrator) Schema(ctx context.Context) (Schema, error) {
+	schema := Schema{Latest: m.Latest(), Pending: []int{}}
+
+	applied := make(map[int]bool)
+	err := func() error {
+		rows, err := m.pool.Query(ctx, `SELECT version FROM schema_migrations;`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version int
+			if err := rows.Scan(&version); err != nil {
+				return err
+			}
+			applied[version] = true
+			if version > schema.Version {
+				schema.Version = version
+			}
+		}
+		return rows.Err()
+	}()
+	var pgErr *pgconn.PgError
+	if err != nil && !(errors.As(err, &pgErr) && pgErr.Code == undefinedTable) {
+		return Schema{}, err
+	}
+
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			schema.Pending = append(schema.Pending, migration.Version)
+		}
+	}
+	return schema, nil
+}
+
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, lockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1);`, lockKey)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations
+	(
+		version    INT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	);`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		result[version] = appliedAt
+	}
+	return result, rows.Err()
+}
+
+func inTx(ctx context.Context, conn *pgxpool.Conn, fn func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
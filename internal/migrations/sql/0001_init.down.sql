@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS users_forum;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS post;
+DROP TABLE IF EXISTS thread;
+DROP TABLE IF EXISTS forum;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS insertVotes();
+DROP FUNCTION IF EXISTS updatePostUserForum();
+DROP FUNCTION IF EXISTS updateThreadUserForum();
+DROP FUNCTION IF EXISTS updateVotes();
+DROP FUNCTION IF EXISTS updateCountOfThreads();
+DROP FUNCTION IF EXISTS updatePath();
@@ -1,7 +1,7 @@
 CREATE EXTENSION IF NOT EXISTS citext;
 
 
-CREATE UNLOGGED TABLE users
+CREATE UNLOGGED TABLE IF NOT EXISTS users
 (
     Nickname citext Primary Key,
     FullName text NOT NULL,
@@ -9,7 +9,7 @@ CREATE UNLOGGED TABLE users
     Email    citext UNIQUE
 );
 
-CREATE UNLOGGED TABLE forum
+CREATE UNLOGGED TABLE IF NOT EXISTS forum
 (
     Slug    citext PRIMARY KEY,
     "user"  citext,
@@ -18,7 +18,7 @@ CREATE UNLOGGED TABLE forum
     Threads INT    DEFAULT 0
 );
 
-CREATE UNLOGGED TABLE thread
+CREATE UNLOGGED TABLE IF NOT EXISTS thread
 (
     id      SERIAL PRIMARY KEY,
     Title   text not null,
@@ -30,7 +30,7 @@ CREATE UNLOGGED TABLE thread
     Votes   INT default 0
 );
 
-CREATE UNLOGGED TABLE post
+CREATE UNLOGGED TABLE IF NOT EXISTS post
 (
     id       BIGSERIAL PRIMARY KEY,
     Author citext,
@@ -46,7 +46,7 @@ CREATE UNLOGGED TABLE post
     FOREIGN KEY (author) REFERENCES "users"  (nickname)
 );
 
-CREATE UNLOGGED TABLE votes
+CREATE UNLOGGED TABLE IF NOT EXISTS votes
 (
     id     BIGSERIAL PRIMARY KEY,
     Author citext REFERENCES "users" (nickname),
@@ -56,7 +56,7 @@ CREATE UNLOGGED TABLE votes
     UNIQUE (Author, Thread)
 );
 
-CREATE UNLOGGED TABLE users_forum
+CREATE UNLOGGED TABLE IF NOT EXISTS users_forum
 (
     nickname citext NOT NULL,
     fullname TEXT NOT NULL,
@@ -152,6 +152,7 @@ $update_path$ LANGUAGE plpgsql;
 
 
 
+DROP TRIGGER IF EXISTS addThreadInForum ON thread;
 CREATE TRIGGER addThreadInForum
     BEFORE INSERT
     ON thread
@@ -159,30 +160,35 @@ CREATE TRIGGER addThreadInForum
 EXECUTE PROCEDURE updateCountOfThreads();
 
 
+DROP TRIGGER IF EXISTS add_voice ON votes;
 CREATE TRIGGER add_voice
     BEFORE INSERT
     ON votes
     FOR EACH ROW
 EXECUTE PROCEDURE insertVotes();
 
+DROP TRIGGER IF EXISTS edit_voice ON votes;
 CREATE TRIGGER edit_voice
     BEFORE UPDATE
     ON votes
     FOR EACH ROW
 EXECUTE PROCEDURE updateVotes();
 
+DROP TRIGGER IF EXISTS update_path_trigger ON post;
 CREATE TRIGGER update_path_trigger
     BEFORE INSERT
     ON post
     FOR EACH ROW
 EXECUTE PROCEDURE updatePath();
 
+DROP TRIGGER IF EXISTS post_insert_user_forum ON post;
 CREATE TRIGGER post_insert_user_forum
     AFTER INSERT
     ON post
     FOR EACH ROW
 EXECUTE PROCEDURE updatePostUserForum();
 
+DROP TRIGGER IF EXISTS thread_insert_user_forum ON thread;
 CREATE TRIGGER thread_insert_user_forum
     AFTER INSERT
     ON thread
@@ -220,6 +226,3 @@ create unique index if not exists vote_unique on votes (Author, Thread);
 ANALYZE post;
 ANALYZE users_forum;
 ANALYZE thread;
-
-
-
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS thr_forum_date_id;
@@ -0,0 +1,2 @@
+-- Cursor pages over a forum's threads compare (created, id).
+CREATE INDEX IF NOT EXISTS thr_forum_date_id ON thread (forum, created, id);
@@ -0,0 +1,14 @@
+-- Users created before this migration have no password and cannot log in.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash text;
+
+-- Only a SHA-256 of the bearer token is stored, a leaked table does not
+-- leak usable sessions.
+CREATE UNLOGGED TABLE IF NOT EXISTS sessions
+(
+    token_hash bytea PRIMARY KEY,
+    nickname   citext                   NOT NULL REFERENCES "users" (nickname) ON DELETE CASCADE,
+    created    timestamp with time zone NOT NULL DEFAULT now(),
+    expires    timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expires ON sessions (expires);
@@ -0,0 +1,10 @@
+-- Forum owners are forum."user"; site admins come from auth.admins. Only
+-- moderators need storing.
+CREATE UNLOGGED TABLE IF NOT EXISTS forum_moderators
+(
+    forum        citext                   NOT NULL REFERENCES "forum" (slug) ON DELETE CASCADE,
+    nickname     citext                   NOT NULL REFERENCES "users" (nickname) ON DELETE CASCADE,
+    appointed_by citext                   NOT NULL,
+    created      timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (forum, nickname)
+);
@@ -0,0 +1,11 @@
+-- Rolling back brings deleted content back; the triggers restore counters.
+UPDATE thread SET isDeleted = FALSE WHERE isDeleted;
+UPDATE post SET isDeleted = FALSE WHERE isDeleted;
+
+DROP TRIGGER IF EXISTS thread_soft_delete ON thread;
+DROP TRIGGER IF EXISTS post_soft_delete ON post;
+DROP FUNCTION IF EXISTS countDeletedThread();
+DROP FUNCTION IF EXISTS countDeletedPost();
+
+ALTER TABLE thread DROP COLUMN IF EXISTS isDeleted;
+ALTER TABLE post DROP COLUMN IF EXISTS isDeleted;
@@ -0,0 +1,51 @@
+ALTER TABLE post ADD COLUMN IF NOT EXISTS isDeleted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE thread ADD COLUMN IF NOT EXISTS isDeleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Deleted posts keep their row and path so replies stay in the tree; only
+-- the forum counter forgets them. Posts of a deleted thread were already
+-- subtracted together with the thread.
+CREATE OR REPLACE FUNCTION countDeletedPost() RETURNS TRIGGER AS
+$count_deleted_post$
+BEGIN
+    IF EXISTS(SELECT 1 FROM thread WHERE id = NEW.thread AND isDeleted) THEN
+        RETURN NEW;
+    END IF;
+    IF NEW.isDeleted THEN
+        UPDATE forum SET Posts = Posts - 1 WHERE slug = NEW.forum;
+    ELSE
+        UPDATE forum SET Posts = Posts + 1 WHERE slug = NEW.forum;
+    END IF;
+    RETURN NEW;
+end
+$count_deleted_post$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION countDeletedThread() RETURNS TRIGGER AS
+$count_deleted_thread$
+DECLARE
+    live BIGINT;
+BEGIN
+    SELECT count(*) FROM post WHERE thread = NEW.id AND NOT isDeleted INTO live;
+    IF NEW.isDeleted THEN
+        UPDATE forum SET Threads = Threads - 1, Posts = Posts - live WHERE slug = NEW.forum;
+    ELSE
+        UPDATE forum SET Threads = Threads + 1, Posts = Posts + live WHERE slug = NEW.forum;
+    END IF;
+    RETURN NEW;
+end
+$count_deleted_thread$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_soft_delete ON post;
+CREATE TRIGGER post_soft_delete
+    AFTER UPDATE OF isDeleted
+    ON post
+    FOR EACH ROW
+    WHEN (OLD.isDeleted IS DISTINCT FROM NEW.isDeleted)
+EXECUTE PROCEDURE countDeletedPost();
+
+DROP TRIGGER IF EXISTS thread_soft_delete ON thread;
+CREATE TRIGGER thread_soft_delete
+    AFTER UPDATE OF isDeleted
+    ON thread
+    FOR EACH ROW
+    WHEN (OLD.isDeleted IS DISTINCT FROM NEW.isDeleted)
+EXECUTE PROCEDURE countDeletedThread();
@@ -0,0 +1,12 @@
+-- Every edit keeps the message it replaced. editor is NULL for edits made
+-- without a session.
+CREATE UNLOGGED TABLE IF NOT EXISTS post_revisions
+(
+    id      bigserial PRIMARY KEY,
+    post    bigint                   NOT NULL REFERENCES "post" (id) ON DELETE CASCADE,
+    editor  citext,
+    message text                     NOT NULL,
+    created timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS post_revisions_post ON post_revisions (post, id);
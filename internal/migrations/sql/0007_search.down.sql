@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS thread_search;
+DROP INDEX IF EXISTS post_search;
@@ -0,0 +1,5 @@
+-- Search uses the 'simple' configuration: forums mix languages, so words are
+-- only lower-cased, never stemmed. Queries must repeat these expressions
+-- verbatim for the indexes to apply.
+CREATE INDEX IF NOT EXISTS post_search ON post USING GIN (to_tsvector('simple', message));
+CREATE INDEX IF NOT EXISTS thread_search ON thread USING GIN (to_tsvector('simple', title || ' ' || message));
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS thread_votes_event ON thread;
+DROP TRIGGER IF EXISTS post_edited_event ON post;
+DROP TRIGGER IF EXISTS post_created_event ON post;
+DROP FUNCTION IF EXISTS notifyVotes();
+DROP FUNCTION IF EXISTS notifyPost();
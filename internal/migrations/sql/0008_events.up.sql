@@ -0,0 +1,44 @@
+-- Changes are announced on the forum_events channel for the event streams.
+-- Payloads carry ids only: NOTIFY payloads are limited to 8000 bytes and a
+-- post message is not.
+CREATE OR REPLACE FUNCTION notifyPost() RETURNS TRIGGER AS
+$notify_post$
+BEGIN
+    PERFORM pg_notify('forum_events', json_build_object(
+        'type', CASE WHEN TG_OP = 'INSERT' THEN 'post' ELSE 'edit' END,
+        'id', NEW.id, 'thread', NEW.thread, 'forum', NEW.forum)::text);
+    RETURN NULL;
+end
+$notify_post$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notifyVotes() RETURNS TRIGGER AS
+$notify_votes$
+BEGIN
+    PERFORM pg_notify('forum_events', json_build_object(
+        'type', 'vote', 'thread', NEW.id, 'forum', NEW.forum, 'votes', NEW.votes)::text);
+    RETURN NULL;
+end
+$notify_votes$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_created_event ON post;
+CREATE TRIGGER post_created_event
+    AFTER INSERT
+    ON post
+    FOR EACH ROW
+EXECUTE PROCEDURE notifyPost();
+
+DROP TRIGGER IF EXISTS post_edited_event ON post;
+CREATE TRIGGER post_edited_event
+    AFTER UPDATE OF message
+    ON post
+    FOR EACH ROW
+    WHEN (OLD.message IS DISTINCT FROM NEW.message)
+EXECUTE PROCEDURE notifyPost();
+
+DROP TRIGGER IF EXISTS thread_votes_event ON thread;
+CREATE TRIGGER thread_votes_event
+    AFTER UPDATE OF votes
+    ON thread
+    FOR EACH ROW
+    WHEN (OLD.votes IS DISTINCT FROM NEW.votes)
+EXECUTE PROCEDURE notifyVotes();
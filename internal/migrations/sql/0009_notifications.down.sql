@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS post_notifications ON post;
+DROP FUNCTION IF EXISTS notifyRepliesAndMentions();
+DROP TABLE IF EXISTS notifications;
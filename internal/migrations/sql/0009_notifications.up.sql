@@ -0,0 +1,44 @@
+CREATE UNLOGGED TABLE IF NOT EXISTS notifications
+(
+    id       bigserial PRIMARY KEY,
+    nickname citext                   NOT NULL REFERENCES "users" (nickname) ON DELETE CASCADE,
+    kind     text                     NOT NULL,
+    post     bigint                   NOT NULL REFERENCES "post" (id) ON DELETE CASCADE,
+    author   citext                   NOT NULL,
+    created  timestamp with time zone NOT NULL DEFAULT now(),
+    read     boolean                  NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_inbox ON notifications (nickname, id);
+CREATE INDEX IF NOT EXISTS notifications_unread ON notifications (nickname) WHERE NOT read;
+
+-- One statement-level trigger per INSERT keeps bulk post creation cheap. A
+-- reply notifies the parent's author; @nickname notifies existing users
+-- other than the author and the replied-to user, who already got a reply.
+CREATE OR REPLACE FUNCTION notifyRepliesAndMentions() RETURNS TRIGGER AS
+$notify_replies$
+BEGIN
+    INSERT INTO notifications (nickname, kind, post, author)
+    SELECT parent.author, 'reply', p.id, p.author
+    FROM inserted p
+             JOIN post parent ON parent.id = p.parent
+    WHERE parent.author <> p.author;
+
+    INSERT INTO notifications (nickname, kind, post, author)
+    SELECT DISTINCT u.nickname, 'mention', p.id, p.author
+    FROM inserted p
+             CROSS JOIN LATERAL regexp_matches(p.message, '@([A-Za-z0-9_.]*[A-Za-z0-9_])', 'g') AS m(name)
+             JOIN users u ON u.nickname = m.name[1]::citext
+    WHERE u.nickname <> p.author
+      AND NOT EXISTS(SELECT 1 FROM post parent WHERE parent.id = p.parent AND parent.author = u.nickname);
+    RETURN NULL;
+end
+$notify_replies$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_notifications ON post;
+CREATE TRIGGER post_notifications
+    AFTER INSERT
+    ON post
+    REFERENCING NEW TABLE AS inserted
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE notifyRepliesAndMentions();
@@ -0,0 +1,15 @@
+-- A row with status 0 is a request still in progress.
+CREATE UNLOGGED TABLE IF NOT EXISTS idempotency_keys
+(
+    scope        citext                   NOT NULL,
+    key          text                     NOT NULL,
+    fingerprint  bytea                    NOT NULL,
+    status       int                      NOT NULL DEFAULT 0,
+    content_type text                     NOT NULL DEFAULT '',
+    body         bytea,
+    created      timestamp with time zone NOT NULL DEFAULT now(),
+    expires      timestamp with time zone NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires ON idempotency_keys (expires);
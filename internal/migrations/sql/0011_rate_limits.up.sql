@@ -0,0 +1,11 @@
+-- Token buckets of the postgres rate limit store. allowed is the outcome of
+-- the last take.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits
+(
+    key     text                     PRIMARY KEY,
+    tokens  double precision         NOT NULL,
+    allowed boolean                  NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_updated ON rate_limits (updated);
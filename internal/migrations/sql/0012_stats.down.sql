@@ -0,0 +1,17 @@
+DO
+$drop_stats_triggers$
+    DECLARE
+        counted TEXT;
+    BEGIN
+        FOREACH counted IN ARRAY ARRAY ['users', 'forum', 'thread', 'post', 'votes']
+            LOOP
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_insert', counted);
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_delete', counted);
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_truncate', counted);
+            END LOOP;
+    END
+$drop_stats_triggers$;
+
+DROP FUNCTION IF EXISTS countRows();
+DROP FUNCTION IF EXISTS resetRows();
+DROP TABLE IF EXISTS stats;
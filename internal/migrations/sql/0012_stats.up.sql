@@ -0,0 +1,71 @@
+-- Row counts for /api/service/status, kept by statement-level triggers so
+-- that reading them does not scan the tables. Each counter is split into
+-- shards picked by backend pid: concurrent writers mostly touch different
+-- rows and do not queue behind one hot row until commit.
+CREATE UNLOGGED TABLE IF NOT EXISTS stats
+(
+    name  text   NOT NULL,
+    shard int    NOT NULL,
+    value bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (name, shard)
+);
+
+CREATE OR REPLACE FUNCTION countRows() RETURNS TRIGGER AS
+$count_rows$
+DECLARE
+    delta BIGINT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT count(*) FROM inserted INTO delta;
+    ELSE
+        SELECT -count(*) FROM deleted INTO delta;
+    END IF;
+    IF delta <> 0 THEN
+        INSERT INTO stats (name, shard, value)
+        VALUES (TG_TABLE_NAME, pg_backend_pid() % 16, delta)
+        ON CONFLICT (name, shard) DO UPDATE SET value = stats.value + EXCLUDED.value;
+    END IF;
+    RETURN NULL;
+end
+$count_rows$ LANGUAGE plpgsql;
+
+-- TRUNCATE skips row triggers, cascaded tables included.
+CREATE OR REPLACE FUNCTION resetRows() RETURNS TRIGGER AS
+$reset_rows$
+BEGIN
+    DELETE FROM stats WHERE name = TG_TABLE_NAME;
+    RETURN NULL;
+end
+$reset_rows$ LANGUAGE plpgsql;
+
+DO
+$create_stats_triggers$
+    DECLARE
+        counted TEXT;
+    BEGIN
+        FOREACH counted IN ARRAY ARRAY ['users', 'forum', 'thread', 'post', 'votes']
+            LOOP
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_insert', counted);
+                EXECUTE format('CREATE TRIGGER %I AFTER INSERT ON %I REFERENCING NEW TABLE AS inserted '
+                                   'FOR EACH STATEMENT EXECUTE PROCEDURE countRows()', counted || '_stats_insert', counted);
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_delete', counted);
+                EXECUTE format('CREATE TRIGGER %I AFTER DELETE ON %I REFERENCING OLD TABLE AS deleted '
+                                   'FOR EACH STATEMENT EXECUTE PROCEDURE countRows()', counted || '_stats_delete', counted);
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_truncate', counted);
+                EXECUTE format('CREATE TRIGGER %I AFTER TRUNCATE ON %I '
+                                   'FOR EACH STATEMENT EXECUTE PROCEDURE resetRows()', counted || '_stats_truncate', counted);
+            END LOOP;
+    END
+$create_stats_triggers$;
+
+DELETE FROM stats;
+INSERT INTO stats (name, shard, value)
+SELECT 'users', 0, count(*) FROM users
+UNION ALL
+SELECT 'forum', 0, count(*) FROM forum
+UNION ALL
+SELECT 'thread', 0, count(*) FROM thread
+UNION ALL
+SELECT 'post', 0, count(*) FROM post
+UNION ALL
+SELECT 'votes', 0, count(*) FROM votes;
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS post_created_event ON post;
+CREATE TRIGGER post_created_event
+    AFTER INSERT
+    ON post
+    FOR EACH ROW
+EXECUTE PROCEDURE notifyPost();
+
+DROP FUNCTION IF EXISTS notifyPosts();
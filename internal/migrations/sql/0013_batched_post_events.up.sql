@@ -0,0 +1,21 @@
+-- One notification per inserted batch instead of one per post: pg_notify is
+-- paid for whether anybody listens or not. A batch is announced per thread
+-- by the range of its ids; posts of other threads may fall in between.
+CREATE OR REPLACE FUNCTION notifyPosts() RETURNS TRIGGER AS
+$notify_posts$
+BEGIN
+    PERFORM pg_notify('forum_events', json_build_object(
+        'type', 'post', 'thread', p.thread, 'forum', p.forum, 'first', min(p.id), 'last', max(p.id))::text)
+    FROM inserted p
+    GROUP BY p.thread, p.forum;
+    RETURN NULL;
+end
+$notify_posts$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_created_event ON post;
+CREATE TRIGGER post_created_event
+    AFTER INSERT
+    ON post
+    REFERENCING NEW TABLE AS inserted
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE notifyPosts();
@@ -0,0 +1,31 @@
+DROP TRIGGER IF EXISTS thread_stats_soft_delete ON thread;
+DROP TRIGGER IF EXISTS post_stats_soft_delete ON post;
+
+DO
+$restore_stats_triggers$
+    DECLARE
+        counted TEXT;
+    BEGIN
+        FOREACH counted IN ARRAY ARRAY ['thread', 'post']
+            LOOP
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_insert', counted);
+                EXECUTE format('CREATE TRIGGER %I AFTER INSERT ON %I REFERENCING NEW TABLE AS inserted '
+                                   'FOR EACH STATEMENT EXECUTE PROCEDURE countRows()', counted || '_stats_insert', counted);
+                EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', counted || '_stats_delete', counted);
+                EXECUTE format('CREATE TRIGGER %I AFTER DELETE ON %I REFERENCING OLD TABLE AS deleted '
+                                   'FOR EACH STATEMENT EXECUTE PROCEDURE countRows()', counted || '_stats_delete', counted);
+            END LOOP;
+    END
+$restore_stats_triggers$;
+
+DROP FUNCTION IF EXISTS countDeletedThreadStats();
+DROP FUNCTION IF EXISTS countDeletedPostStats();
+DROP FUNCTION IF EXISTS countLivePosts();
+DROP FUNCTION IF EXISTS countLiveThreads();
+DROP FUNCTION IF EXISTS addStat(TEXT, BIGINT);
+
+DELETE FROM stats WHERE name IN ('thread', 'post');
+INSERT INTO stats (name, shard, value)
+SELECT 'thread', 0, count(*) FROM thread
+UNION ALL
+SELECT 'post', 0, count(*) FROM post;
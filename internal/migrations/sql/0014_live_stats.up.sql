@@ -0,0 +1,122 @@
+-- Threads and posts are counted in stats only while they are live, as in
+-- the forum counters: a deleted thread takes its posts out with it.
+CREATE OR REPLACE FUNCTION addStat(counted TEXT, delta BIGINT) RETURNS VOID AS
+$add_stat$
+BEGIN
+    IF delta <> 0 THEN
+        INSERT INTO stats (name, shard, value)
+        VALUES (counted, pg_backend_pid() % 16, delta)
+        ON CONFLICT (name, shard) DO UPDATE SET value = stats.value + EXCLUDED.value;
+    END IF;
+end
+$add_stat$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION countLiveThreads() RETURNS TRIGGER AS
+$count_live_threads$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM addStat('thread', (SELECT count(*) FROM inserted WHERE NOT isDeleted));
+    ELSE
+        PERFORM addStat('thread', -(SELECT count(*) FROM deleted WHERE NOT isDeleted));
+    END IF;
+    RETURN NULL;
+end
+$count_live_threads$ LANGUAGE plpgsql;
+
+-- Posts go before their thread, no cascade deletes them after it.
+CREATE OR REPLACE FUNCTION countLivePosts() RETURNS TRIGGER AS
+$count_live_posts$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM addStat('post', (SELECT count(*) FROM inserted p
+                                 WHERE NOT p.isDeleted
+                                   AND NOT EXISTS(SELECT 1 FROM thread t WHERE t.id = p.thread AND t.isDeleted)));
+    ELSE
+        PERFORM addStat('post', -(SELECT count(*) FROM deleted p
+                                  WHERE NOT p.isDeleted
+                                    AND NOT EXISTS(SELECT 1 FROM thread t WHERE t.id = p.thread AND t.isDeleted)));
+    END IF;
+    RETURN NULL;
+end
+$count_live_posts$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION countDeletedPostStats() RETURNS TRIGGER AS
+$count_deleted_post_stats$
+BEGIN
+    IF NOT EXISTS(SELECT 1 FROM thread WHERE id = NEW.thread AND isDeleted) THEN
+        PERFORM addStat('post', CASE WHEN NEW.isDeleted THEN -1 ELSE 1 END);
+    END IF;
+    RETURN NULL;
+end
+$count_deleted_post_stats$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION countDeletedThreadStats() RETURNS TRIGGER AS
+$count_deleted_thread_stats$
+DECLARE
+    live BIGINT;
+BEGIN
+    SELECT count(*) FROM post WHERE thread = NEW.id AND NOT isDeleted INTO live;
+    IF NEW.isDeleted THEN
+        PERFORM addStat('thread', -1);
+        PERFORM addStat('post', -live);
+    ELSE
+        PERFORM addStat('thread', 1);
+        PERFORM addStat('post', live);
+    END IF;
+    RETURN NULL;
+end
+$count_deleted_thread_stats$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS thread_stats_insert ON thread;
+CREATE TRIGGER thread_stats_insert
+    AFTER INSERT
+    ON thread
+    REFERENCING NEW TABLE AS inserted
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE countLiveThreads();
+
+DROP TRIGGER IF EXISTS thread_stats_delete ON thread;
+CREATE TRIGGER thread_stats_delete
+    AFTER DELETE
+    ON thread
+    REFERENCING OLD TABLE AS deleted
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE countLiveThreads();
+
+DROP TRIGGER IF EXISTS post_stats_insert ON post;
+CREATE TRIGGER post_stats_insert
+    AFTER INSERT
+    ON post
+    REFERENCING NEW TABLE AS inserted
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE countLivePosts();
+
+DROP TRIGGER IF EXISTS post_stats_delete ON post;
+CREATE TRIGGER post_stats_delete
+    AFTER DELETE
+    ON post
+    REFERENCING OLD TABLE AS deleted
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE countLivePosts();
+
+DROP TRIGGER IF EXISTS post_stats_soft_delete ON post;
+CREATE TRIGGER post_stats_soft_delete
+    AFTER UPDATE OF isDeleted
+    ON post
+    FOR EACH ROW
+    WHEN (OLD.isDeleted IS DISTINCT FROM NEW.isDeleted)
+EXECUTE PROCEDURE countDeletedPostStats();
+
+DROP TRIGGER IF EXISTS thread_stats_soft_delete ON thread;
+CREATE TRIGGER thread_stats_soft_delete
+    AFTER UPDATE OF isDeleted
+    ON thread
+    FOR EACH ROW
+    WHEN (OLD.isDeleted IS DISTINCT FROM NEW.isDeleted)
+EXECUTE PROCEDURE countDeletedThreadStats();
+
+DELETE FROM stats WHERE name IN ('thread', 'post');
+INSERT INTO stats (name, shard, value)
+SELECT 'thread', 0, count(*) FROM thread WHERE NOT isDeleted
+UNION ALL
+SELECT 'post', 0, count(*) FROM post p JOIN thread t ON t.id = p.thread WHERE NOT p.isDeleted AND NOT t.isDeleted;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idempotency_keys_path;
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS path;
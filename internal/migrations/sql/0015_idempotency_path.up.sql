@@ -0,0 +1,6 @@
+-- path is the request path a key was used on, so that clearing a forum drops
+-- the keys of its routes and threads only. Keys from before keep '' and
+-- expire as usual.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS path citext NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_path ON idempotency_keys (path);
@@ -0,0 +1,79 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"technopark-dbms-forum/internal/httpio"
+	domain "technopark-dbms-forum/internal/notification"
+	"technopark-dbms-forum/models"
+)
+
+const unreadCountHeader = "X-Unread-Count"
+
+type NotificationHandler struct {
+	NotificationUseCase domain.NotificationUseCase
+}
+
+func NewNotificationHandler(r *mux.Router, notificationUseCase domain.NotificationUseCase) {
+	handler := &NotificationHandler{NotificationUseCase: notificationUseCase}
+
+	r.HandleFunc("/api/user/{nickname}/notifications", handler.Notifications).Methods(http.MethodGet).Name("user_notifications")
+	r.HandleFunc("/api/user/{nickname}/notifications/read", handler.MarkAllRead).Methods(http.MethodPost).Name("user_notifications_read")
+	r.HandleFunc("/api/user/{nickname}/notifications/{id}/read", handler.MarkRead).Methods(http.MethodPost).Name("user_notification_read")
+}
+
+func (n *NotificationHandler) Notifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	var params models.Parameters
+	var err error
+	params.Limit, err = strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		params.Limit = 100
+	}
+	params.Since = query.Get("since")
+	unread, _ := strconv.ParseBool(query.Get("unread"))
+
+	notifications, count, err := n.NotificationUseCase.Notifications(r.Context(), mux.Vars(r)["nickname"], unread, params)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set(unreadCountHeader, strconv.Itoa(count))
+	httpio.WriteList(w, len(notifications), notifications)
+}
+
+func (n *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	unread, err := n.NotificationUseCase.MarkAllRead(r.Context(), mux.Vars(r)["nickname"])
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteJSON(w, http.StatusOK, unread)
+}
+
+func (n *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil || id <= 0 {
+		httpio.WriteError(w, models.ErrBadRequest.WithMessage("Notification id must be a positive number: %s", vars["id"]))
+		return
+	}
+
+	unread, err := n.NotificationUseCase.MarkRead(r.Context(), vars["nickname"], id)
+	if err != nil {
+		httpio.WriteError(w, err)
+		return
+	}
+
+	httpio.WriteJSON(w, http.StatusOK, unread)
+}
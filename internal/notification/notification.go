@@ -0,0 +1,22 @@
+package notification
+
+import (
+	"context"
+
+	"technopark-dbms-forum/models"
+)
+
+type NotificationUseCase interface {
+	// Notifications returns a page of the inbox, newest first, along with the
+	// number of unread notifications.
+	Notifications(ctx context.Context, nickname string, unread bool, params models.Parameters) ([]models.Notification, int, error)
+	MarkRead(ctx context.Context, nickname string, id int) (models.Unread, error)
+	MarkAllRead(ctx context.Context, nickname string) (models.Unread, error)
+}
+
+type NotificationRepository interface {
+	SelectNotifications(ctx context.Context, nickname string, unread bool, params models.Parameters) ([]models.Notification, error)
+	CountUnread(ctx context.Context, nickname string) (int, error)
+	// UpdateRead marks one notification read, or all of them when id is 0.
+	UpdateRead(ctx context.Context, nickname string, id int) error
+}
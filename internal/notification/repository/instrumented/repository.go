@@ -0,0 +1,40 @@
+package instrumented
+
+import (
+	"context"
+
+	"technopark-dbms-forum/internal/metrics"
+	domain "technopark-dbms-forum/internal/notification"
+	"technopark-dbms-forum/models"
+)
+
+// notificationRepository times every call of the wrapped repository.
+type notificationRepository struct {
+	next    domain.NotificationRepository
+	metrics *metrics.Metrics
+}
+
+func NewNotificationRepository(next domain.NotificationRepository, m *metrics.Metrics) domain.NotificationRepository {
+	return &notificationRepository{next: next, metrics: m}
+}
+
+func (r *notificationRepository) SelectNotifications(ctx context.Context, nickname string, unread bool, params models.Parameters) ([]models.Notification, error) {
+	done := r.metrics.Query("notification", "SelectNotifications")
+	result, err := r.next.SelectNotifications(ctx, nickname, unread, params)
+	done(err)
+	return result, err
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, nickname string) (int, error) {
+	done := r.metrics.Query("notification", "CountUnread")
+	result, err := r.next.CountUnread(ctx, nickname)
+	done(err)
+	return result, err
+}
+
+func (r *notificationRepository) UpdateRead(ctx context.Context, nickname string, id int) error {
+	done := r.metrics.Query("notification", "UpdateRead")
+	err := r.next.UpdateRead(ctx, nickname, id)
+	done(err)
+	return err
+}
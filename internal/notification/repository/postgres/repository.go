@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"technopark-dbms-forum/internal/database"
+	domain "technopark-dbms-forum/internal/notification"
+	"technopark-dbms-forum/models"
+)
+
+type postgresNotificationRepository struct {
+	Conn database.Conn
+}
+
+func NewPostgresNotificationRepository(Conn database.Conn) domain.NotificationRepository {
+	return &postgresNotificationRepository{Conn: Conn}
+}
+
+// Notifications about deleted posts stay in the table but are never shown
+// nor counted.
+func (p *postgresNotificationRepository) SelectNotifications(ctx context.Context, nickname string, unread bool, params models.Parameters) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+
+	args := []interface{}{nickname, unread, params.Limit}
+	since := ""
+	if params.Since != "" {
+		id, err := strconv.Atoi(params.Since)
+		if err != nil {
+			return notifications, models.ErrBadRequest.WithMessage("since must be a notification id: %s", params.Since)
+		}
+		args = append(args, id)
+		since = fmt.Sprintf("AND n.id < $%d", len(args))
+	}
+
+	rows, err := p.Conn.Query(ctx, fmt.Sprintf(`SELECT n.id, n.kind, n.post, p.thread, p.forum, n.author, p.message, n.created, n.read
+		FROM notifications n
+		JOIN post p ON p.id = n.post AND NOT p.isDeleted
+		JOIN thread t ON t.id = p.thread AND NOT t.isDeleted
+		WHERE n.nickname=$1 AND (NOT $2 OR NOT n.read) %s
+		ORDER BY n.id DESC LIMIT NULLIF($3, 0);`, since), args...)
+	if err != nil {
+		return notifications, database.Translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n models.Notification
+		err = rows.Scan(&n.ID, &n.Kind, &n.Post, &n.Thread, &n.Forum, &n.Author, &n.Message, &n.Created, &n.Read)
+		if err != nil {
+			return notifications, database.Translate(err)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, database.Translate(rows.Err())
+}
+
+func (p *postgresNotificationRepository) CountUnread(ctx context.Context, nickname string) (int, error) {
+	var count int
+	err := p.Conn.QueryRow(ctx, `SELECT count(*) FROM notifications n
+		JOIN post p ON p.id = n.post AND NOT p.isDeleted
+		JOIN thread t ON t.id = p.thread AND NOT t.isDeleted
+		WHERE n.nickname=$1 AND NOT n.read;`, nickname).Scan(&count)
+	return count, database.Translate(err)
+}
+
+func (p *postgresNotificationRepository) UpdateRead(ctx context.Context, nickname string, id int) error {
+	if id == 0 {
+		_, err := p.Conn.Exec(ctx, `UPDATE notifications SET read = TRUE WHERE nickname=$1 AND NOT read;`, nickname)
+		return database.Translate(err)
+	}
+
+	tag, err := p.Conn.Exec(ctx, `UPDATE notifications SET read = TRUE WHERE nickname=$1 AND id=$2;`, nickname, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound.WithMessage("Can't find notification %d of %s", id, nickname).
+			WithDetail("id", strconv.Itoa(id))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+
+	"technopark-dbms-forum/internal/auth"
+	domain "technopark-dbms-forum/internal/notification"
+	"technopark-dbms-forum/models"
+)
+
+type NotificationUsecase struct {
+	notificationRepo domain.NotificationRepository
+	auth             auth.AuthUseCase
+}
+
+func NewNotificationUsecase(notificationRepo domain.NotificationRepository, authUseCase auth.AuthUseCase) domain.NotificationUseCase {
+	return &NotificationUsecase{notificationRepo: notificationRepo, auth: authUseCase}
+}
+
+func (n *NotificationUsecase) Notifications(ctx context.Context, nickname string, unread bool, params models.Parameters) ([]models.Notification, int, error) {
+	if err := n.authorize(ctx, nickname); err != nil {
+		return nil, 0, err
+	}
+	notifications, err := n.notificationRepo.SelectNotifications(ctx, nickname, unread, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := n.notificationRepo.CountUnread(ctx, nickname)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, count, nil
+}
+
+func (n *NotificationUsecase) MarkRead(ctx context.Context, nickname string, id int) (models.Unread, error) {
+	if err := n.authorize(ctx, nickname); err != nil {
+		return models.Unread{}, err
+	}
+	if err := n.notificationRepo.UpdateRead(ctx, nickname, id); err != nil {
+		return models.Unread{}, err
+	}
+	count, err := n.notificationRepo.CountUnread(ctx, nickname)
+	return models.Unread{Unread: count}, err
+}
+
+func (n *NotificationUsecase) MarkAllRead(ctx context.Context, nickname string) (models.Unread, error) {
+	return n.MarkRead(ctx, nickname, 0)
+}
+
+// authorize requires a session whatever auth.enforce says: an inbox is
+// private.
+func (n *NotificationUsecase) authorize(ctx context.Context, nickname string) error {
+	if _, ok := auth.Nickname(ctx); !ok {
+		return models.ErrUnauthorized.WithMessage("Log in to read notifications of %s", nickname)
+	}
+	return n.auth.Authorize(ctx, nickname)
+}
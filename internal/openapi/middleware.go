@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/internal/logging"
+)
+
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	// streamed responses are flushed as they go and are not validated.
+	streamed bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	if !r.streamed {
+		r.body.Write(data)
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) Flush() {
+	flusher, ok := r.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	r.streamed = true
+	r.body.Reset()
+	flusher.Flush()
+}
+
+// ValidationMiddleware checks every response against the document and
+// reports mismatches without touching the response. It is meant for test
+// and staging runs, not production traffic.
+func (s *Spec) ValidationMiddleware(log zerolog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			route := mux.CurrentRoute(r)
+			if route == nil || rec.streamed {
+				return
+			}
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				return
+			}
+			if err := s.ValidateResponse(path, r.Method, rec.status, rec.body.Bytes()); err != nil {
+				logging.For(r.Context(), log).Warn().Err(err).Msg("response does not match the spec")
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+//go:embed openapi.json
+var document []byte
+
+var methods = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+
+type Spec struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string              `json:"operationId"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type response struct {
+	Content map[string]struct {
+		Schema schema `json:"schema"`
+	} `json:"content"`
+}
+
+type schema map[string]interface{}
+
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(document, &spec); err != nil {
+		return nil, fmt.Errorf("openapi: %v", err)
+	}
+	return &spec, nil
+}
+
+func (s *Spec) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(document)
+	}
+}
+
+// CheckRoutes fails when a route registered on the router is not described
+// in the document or the document describes an operation nobody serves.
+func (s *Spec) CheckRoutes(router *mux.Router) error {
+	registered := make(map[string]bool)
+	var problems []string
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		routeMethods, err := route.GetMethods()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s has no method restriction", path))
+			return nil
+		}
+		for _, method := range routeMethods {
+			method = strings.ToLower(method)
+			registered[method+" "+path] = true
+			if _, ok := s.Paths[path][method]; !ok {
+				problems = append(problems, fmt.Sprintf("%s %s is not in openapi.json", strings.ToUpper(method), path))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for path, operations := range s.Paths {
+		for _, method := range methods {
+			if _, ok := operations[method]; ok && !registered[method+" "+path] {
+				problems = append(problems, fmt.Sprintf("%s %s is documented but not routed", strings.ToUpper(method), path))
+			}
+		}
+	}
+
+	if len(problems) != 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("openapi: routes out of sync: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateResponse checks a JSON body against the schema documented for the
+// route template, method and status code.
+func (s *Spec) ValidateResponse(path, method string, status int, body []byte) error {
+	op, ok := s.Paths[path][strings.ToLower(method)]
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	resp, ok := op.Responses[fmt.Sprint(status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+	content, ok := resp.Content["application/json"]
+	if !ok {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: %d body is not JSON: %v", method, path, status, err)
+	}
+	if err := s.validate(content.Schema, value, "$"); err != nil {
+		return fmt.Errorf("%s %s: %d body: %v", method, path, status, err)
+	}
+	return nil
+}
+
+func (s *Spec) validate(sch schema, value interface{}, at string) error {
+	if ref, ok := sch["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target, ok := s.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, ref)
+		}
+		return s.validate(target, value, at)
+	}
+
+	if value == nil {
+		if nullable, _ := sch["nullable"].(bool); nullable {
+			return nil
+		}
+	}
+
+	if all, ok := sch["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if err := s.validate(toSchema(sub), value, at); err != nil {
+				return err
+			}
+		}
+	}
+
+	if enum, ok := sch["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if option == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", at, value, enum)
+		}
+	}
+
+	typ, _ := sch["type"].(string)
+	switch typ {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object", at)
+		}
+		if required, ok := sch["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := object[name.(string)]; !ok {
+					return fmt.Errorf("%s: missing %s", at, name)
+				}
+			}
+		}
+		properties, _ := sch["properties"].(map[string]interface{})
+		for name, property := range properties {
+			if fieldValue, ok := object[name]; ok {
+				if err := s.validate(toSchema(property), fieldValue, at+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array", at)
+		}
+		for i, item := range array {
+			if err := s.validate(toSchema(sch["items"]), item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected string", at)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) {
+			return fmt.Errorf("%s: expected integer", at)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected number", at)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", at)
+		}
+	}
+	return nil
+}
+
+func toSchema(value interface{}) schema {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m
+	}
+	return schema{}
+}
@@ -0,0 +1,203 @@
+package openapi_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/internal/app"
+	"technopark-dbms-forum/internal/cursor"
+	"technopark-dbms-forum/internal/forum"
+	"technopark-dbms-forum/internal/health"
+	"technopark-dbms-forum/internal/metrics"
+	"technopark-dbms-forum/internal/openapi"
+	"technopark-dbms-forum/models"
+)
+
+var created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var (
+	testForum  = models.Forum{Title: "Pirates", User: "jack", Slug: "pirates", Posts: 2, Threads: 1}
+	testThread = models.Thread{Id: 42, Title: "Treasure", Author: "jack", Forum: "pirates", Message: "Where?", Votes: 3,
+		Slug: "treasure", Created: created}
+	testUser = models.User{Nickname: "jack", FullName: "Jack Sparrow", About: "Captain", Email: "jack@sea.org"}
+	testPost = models.Post{ID: 7, Author: "jack", Created: created, Forum: "pirates", Message: "Here",
+		Parent: models.JsonNullInt64{NullInt64: sql.NullInt64{Int64: 6, Valid: true}}, Thread: 42}
+)
+
+// fakeForum answers the read endpoints with fixtures; anything else panics
+// through the nil embedded interface.
+type fakeForum struct {
+	forum.ForumUseCase
+}
+
+func (fakeForum) ForumDetails(ctx context.Context, slug string) (models.Forum, error) {
+	if slug != testForum.Slug {
+		return models.Forum{}, models.ForumNotFound(slug)
+	}
+	return testForum, nil
+}
+
+func (fakeForum) ThreadDetails(ctx context.Context, slug string) (models.Thread, error) {
+	return testThread, nil
+}
+
+func (fakeForum) GetUser(ctx context.Context, nickname string) (models.User, error) {
+	return testUser, nil
+}
+
+func (fakeForum) StatusDB(ctx context.Context, exact bool, perForum bool) (models.Status, error) {
+	status := models.Status{User: 1, Forum: 1, Thread: 1, Post: 2, Vote: 3}
+	if perForum {
+		status.Forums = []models.ForumStatus{{Slug: testForum.Slug, Threads: 1, Posts: 2}}
+	}
+	return status, nil
+}
+
+func (fakeForum) PostFullDetails(ctx context.Context, id int, related string) (models.PostFull, error) {
+	post, user, forum, thread := testPost, testUser, testForum, testThread
+	return models.PostFull{Post: &post, Author: &user, Forum: &forum, Thread: &thread}, nil
+}
+
+func (fakeForum) ListThreads(ctx context.Context, slug string, params models.Parameters) ([]models.Thread, error) {
+	return []models.Thread{testThread}, nil
+}
+
+func (fakeForum) GetPostsOfThread(ctx context.Context, threadId int, parameters models.Parameters, sort string) ([]models.Post, error) {
+	return []models.Post{testPost}, nil
+}
+
+func newRouter(t *testing.T, spec *openapi.Spec) *mux.Router {
+	t.Helper()
+	cursors, err := cursor.NewSigner("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	app.Routes(router, app.Services{
+		Forum:   fakeForum{},
+		Cursors: cursors,
+		Health:  health.NewChecker(nil, nil),
+		Metrics: metrics.New(nil),
+		Spec:    spec,
+		Log:     zerolog.Nop(),
+	})
+	return router
+}
+
+func load(t *testing.T) *openapi.Spec {
+	t.Helper()
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestCheckRoutes(t *testing.T) {
+	spec := load(t)
+	if err := spec.CheckRoutes(newRouter(t, spec)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRoutesMissingRoute(t *testing.T) {
+	spec := load(t)
+	full := newRouter(t, spec)
+
+	router := mux.NewRouter()
+	err := full.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetName() == "forum_details" {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		router.Handle(path, route.GetHandler()).Methods(methods...).Name(route.GetName())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = spec.CheckRoutes(router)
+	if err == nil || !strings.Contains(err.Error(), "GET /api/forum/{slug}/details is documented but not routed") {
+		t.Fatalf("expected the removed route to be reported, got %v", err)
+	}
+}
+
+func TestCheckRoutesUndocumentedRoute(t *testing.T) {
+	spec := load(t)
+	router := newRouter(t, spec)
+	router.HandleFunc("/api/undocumented", func(http.ResponseWriter, *http.Request) {}).
+		Methods(http.MethodGet).Name("undocumented")
+
+	err := spec.CheckRoutes(router)
+	if err == nil || !strings.Contains(err.Error(), "GET /api/undocumented is not in openapi.json") {
+		t.Fatalf("expected the undocumented route to be reported, got %v", err)
+	}
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	spec := load(t)
+	router := newRouter(t, spec)
+
+	tests := []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodGet, "/api/forum/pirates/details", http.StatusOK},
+		{http.MethodGet, "/api/forum/unknown/details", http.StatusNotFound},
+		{http.MethodGet, "/api/forum/pirates/threads?limit=1", http.StatusOK},
+		{http.MethodGet, "/api/thread/treasure/details", http.StatusOK},
+		{http.MethodGet, "/api/thread/42/posts?sort=tree", http.StatusOK},
+		{http.MethodGet, "/api/post/7/details?related=user,forum,thread", http.StatusOK},
+		{http.MethodGet, "/api/post/seven/details", http.StatusBadRequest},
+		{http.MethodGet, "/api/user/jack/profile", http.StatusOK},
+		{http.MethodGet, "/api/service/status?forums=true", http.StatusOK},
+		{http.MethodGet, "/api/search?q=treasure&since=best", http.StatusBadRequest},
+		{http.MethodGet, "/healthz", http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.target, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.target, nil)
+			var match mux.RouteMatch
+			if !router.Match(request, &match) {
+				t.Fatal("no route")
+			}
+			path, err := match.Route.GetPathTemplate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if err := spec.ValidateResponse(path, test.method, recorder.Code, recorder.Body.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidateResponseRejectsMismatch(t *testing.T) {
+	spec := load(t)
+	err := spec.ValidateResponse("/api/forum/{slug}/details", http.MethodGet, http.StatusOK, []byte(`{"title": "Pirates"}`))
+	if err == nil {
+		t.Fatal("expected a forum without slug and user to be rejected")
+	}
+}
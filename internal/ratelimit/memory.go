@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many takes pass between drops of idle buckets.
+const sweepEvery = 10000
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill brings the bucket up to now. A bucket that went full is as good
+// as a new one.
+func (b *bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.updated).Seconds() * b.limit.Rate
+	if full := float64(b.limit.Burst); b.tokens > full {
+		b.tokens = full
+	}
+	b.updated = now
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+// NewMemoryStore keeps buckets in this instance only: with several
+// instances a client gets the limit once per instance.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (m *memoryStore) Take(_ context.Context, buckets []Bucket) (bool, time.Duration, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.takes++
+	if m.takes%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	var wait time.Duration
+	found := make([]*bucket, len(buckets))
+	for i, want := range buckets {
+		b, ok := m.buckets[want.Key]
+		if !ok {
+			b = &bucket{tokens: float64(want.Limit.Burst), updated: now, limit: want.Limit}
+			m.buckets[want.Key] = b
+		}
+		b.refill(now)
+		if b.tokens < 1 {
+			if retryAfter := RetryAfter(b.tokens, want.Limit); retryAfter > wait {
+				wait = retryAfter
+			}
+		}
+		found[i] = b
+	}
+	if wait > 0 {
+		return false, wait, nil
+	}
+	for _, b := range found {
+		b.tokens--
+	}
+	return true, 0, nil
+}
+
+func (m *memoryStore) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryStoreTakesAllOrNothing(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	// A rate this low adds no token while the test runs.
+	tight := Bucket{Key: "tight", Limit: Limit{Rate: 0.001, Burst: 1}}
+	loose := Bucket{Key: "loose", Limit: Limit{Rate: 0.001, Burst: 2}}
+
+	takes := []struct {
+		buckets []Bucket
+		allowed bool
+	}{
+		{[]Bucket{tight, loose}, true},
+		{[]Bucket{tight, loose}, false},
+		{[]Bucket{tight, loose}, false},
+		// The denied takes left the last token of loose in place.
+		{[]Bucket{loose}, true},
+		{[]Bucket{loose}, false},
+	}
+	for i, take := range takes {
+		allowed, wait, err := store.Take(ctx, take.buckets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != take.allowed {
+			t.Fatalf("take %d: allowed %v, want %v", i, allowed, take.allowed)
+		}
+		if !allowed && wait <= 0 {
+			t.Errorf("take %d: denied without a wait", i)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package ratelimit throttles route groups with token buckets kept per
+// client IP and per logged in user.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"technopark-dbms-forum/configs"
+	"technopark-dbms-forum/internal/auth"
+	"technopark-dbms-forum/internal/httpio"
+	"technopark-dbms-forum/internal/logging"
+	"technopark-dbms-forum/models"
+)
+
+type Limit struct {
+	// Rate is in tokens per second.
+	Rate  float64
+	Burst int
+}
+
+// Bucket is the token bucket of key, filled at limit.
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+type Store interface {
+	// Take removes a token from every bucket, or from none of them when one
+	// is empty, so that a denied request costs nothing. Then it reports how
+	// long until every bucket holds a token.
+	Take(ctx context.Context, buckets []Bucket) (bool, time.Duration, error)
+}
+
+type group struct {
+	name  string
+	limit Limit
+}
+
+// Middleware limits the routes of every configured group. A failing store
+// lets requests through rather than taking the service down with it.
+func Middleware(store Store, cfg configs.RateLimitConfig, log zerolog.Logger) mux.MiddlewareFunc {
+	groups := make(map[string][]group)
+	for name, g := range cfg.Groups {
+		for _, route := range g.Routes {
+			groups[route] = append(groups[route], group{name: name, limit: Limit{Rate: g.Rate, Burst: g.Burst}})
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil || len(groups[route.GetName()]) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			keys := []string{"ip:" + httpio.ClientIP(r, cfg.TrustProxy)}
+			if nickname, ok := auth.Nickname(r.Context()); ok {
+				keys = append(keys, "user:"+strings.ToLower(nickname))
+			}
+
+			var buckets []Bucket
+			for _, g := range groups[route.GetName()] {
+				for _, key := range keys {
+					buckets = append(buckets, Bucket{Key: g.name + ":" + key, Limit: g.limit})
+				}
+			}
+			allowed, wait, err := store.Take(r.Context(), buckets)
+			if err != nil {
+				logging.For(r.Context(), log).Error().Err(err).Str("route", route.GetName()).Msg("rate limit store failed")
+			}
+			if allowed || err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			httpio.WriteError(w, models.ErrTooManyRequests.WithMessage("Rate limit exceeded, retry in %d s", seconds))
+		})
+	}
+}
+
+// RetryAfter is how long a bucket holding tokens takes to refill up to one
+// token, at least a second as Retry-After counts whole seconds.
+func RetryAfter(tokens float64, limit Limit) time.Duration {
+	wait := time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
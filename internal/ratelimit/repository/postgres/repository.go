@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/internal/ratelimit"
+)
+
+// idleAfter is how long an untouched bucket is kept. Any bucket is full again
+// long before that with sane limits, so dropping it changes nothing.
+const idleAfter = time.Hour
+
+type postgresRateLimitStore struct {
+	Conn database.Conn
+
+	mu    sync.Mutex
+	swept time.Time
+}
+
+// NewPostgresRateLimitStore shares buckets between instances. The refill is
+// computed by the database clock, so instance clocks do not matter.
+func NewPostgresRateLimitStore(Conn database.Conn) ratelimit.Store {
+	return &postgresRateLimitStore{Conn: Conn, swept: time.Now()}
+}
+
+// Take refills the buckets in key order, which keeps concurrent takes from
+// deadlocking on the row locks, and takes the tokens in the same transaction
+// only when every bucket has one.
+func (p *postgresRateLimitStore) Take(ctx context.Context, buckets []ratelimit.Bucket) (bool, time.Duration, error) {
+	if err := p.sweep(ctx); err != nil {
+		return false, 0, err
+	}
+
+	sorted := append([]ratelimit.Bucket(nil), buckets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	tx, err := p.Conn.Begin(ctx)
+	if err != nil {
+		return false, 0, database.Translate(err)
+	}
+	defer tx.Rollback(ctx)
+
+	var wait time.Duration
+	keys := make([]string, len(sorted))
+	for i, bucket := range sorted {
+		keys[i] = bucket.Key
+		var tokens float64
+		err := tx.QueryRow(ctx, `INSERT INTO rate_limits AS b (key, tokens, allowed) VALUES ($1, $3, TRUE)
+			ON CONFLICT (key) DO UPDATE SET
+				tokens = LEAST($3, b.tokens + extract(epoch FROM now() - b.updated) * $2),
+				updated = now()
+			RETURNING tokens;`, bucket.Key, bucket.Limit.Rate, float64(bucket.Limit.Burst)).Scan(&tokens)
+		if err != nil {
+			return false, 0, database.Translate(err)
+		}
+		if tokens < 1 {
+			if retryAfter := ratelimit.RetryAfter(tokens, bucket.Limit); retryAfter > wait {
+				wait = retryAfter
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE rate_limits SET allowed = $2, tokens = tokens - CASE WHEN $2 THEN 1 ELSE 0 END
+		WHERE key = ANY($1);`, keys, wait == 0)
+	if err != nil {
+		return false, 0, database.Translate(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, 0, database.Translate(err)
+	}
+	return wait == 0, wait, nil
+}
+
+// sweep drops idle buckets about once a minute per instance.
+func (p *postgresRateLimitStore) sweep(ctx context.Context) error {
+	p.mu.Lock()
+	if time.Since(p.swept) < time.Minute {
+		p.mu.Unlock()
+		return nil
+	}
+	p.swept = time.Now()
+	p.mu.Unlock()
+
+	_, err := p.Conn.Exec(ctx, `DELETE FROM rate_limits WHERE updated < now() - $1 * interval '1 second';`, idleAfter.Seconds())
+	return database.Translate(err)
+}
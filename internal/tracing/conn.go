@@ -0,0 +1,158 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"technopark-dbms-forum/internal/database"
+	"technopark-dbms-forum/models"
+)
+
+// conn starts a span per statement. Arguments are left out of the spans as
+// they carry user content.
+type conn struct {
+	next database.Conn
+}
+
+func Conn(next database.Conn) database.Conn {
+	return &conn{next: next}
+}
+
+func (c *conn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startStatement(ctx, sql)
+	tag, err := c.next.Exec(ctx, sql, arguments...)
+	endStatement(span, err)
+	return tag, err
+}
+
+func (c *conn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startStatement(ctx, sql)
+	result, err := c.next.Query(ctx, sql, args...)
+	if err != nil {
+		endStatement(span, err)
+		return result, err
+	}
+	return &rows{Rows: result, span: span}, nil
+}
+
+func (c *conn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startStatement(ctx, sql)
+	return &row{row: c.next.QueryRow(ctx, sql, args...), span: span}
+}
+
+func (c *conn) Begin(ctx context.Context) (pgx.Tx, error) {
+	return wrapTx(c.next.Begin(ctx))
+}
+
+func (c *conn) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return wrapTx(c.next.BeginTx(ctx, txOptions))
+}
+
+// tx starts a span per statement of the transaction like conn does; the
+// rest of pgx.Tx is passed through.
+type tx struct {
+	pgx.Tx
+}
+
+func wrapTx(next pgx.Tx, err error) (pgx.Tx, error) {
+	if err != nil {
+		return next, err
+	}
+	return &tx{Tx: next}, nil
+}
+
+func (t *tx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return wrapTx(t.Tx.Begin(ctx))
+}
+
+func (t *tx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startStatement(ctx, sql)
+	tag, err := t.Tx.Exec(ctx, sql, arguments...)
+	endStatement(span, err)
+	return tag, err
+}
+
+func (t *tx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startStatement(ctx, sql)
+	result, err := t.Tx.Query(ctx, sql, args...)
+	if err != nil {
+		endStatement(span, err)
+		return result, err
+	}
+	return &rows{Rows: result, span: span}, nil
+}
+
+func (t *tx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startStatement(ctx, sql)
+	return &row{row: t.Tx.QueryRow(ctx, sql, args...), span: span}
+}
+
+// rows ends the span once the result is read or closed, so the span covers
+// the fetching too.
+type rows struct {
+	pgx.Rows
+	span  trace.Span
+	ended bool
+}
+
+func (r *rows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *rows) Close() {
+	r.Rows.Close()
+	r.end()
+}
+
+func (r *rows) end() {
+	if r.ended {
+		return
+	}
+	r.ended = true
+	endStatement(r.span, r.Rows.Err())
+}
+
+type row struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r *row) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	endStatement(r.span, err)
+	return err
+}
+
+func startStatement(ctx context.Context, sql string) (context.Context, trace.Span) {
+	sql = strings.TrimSpace(sql)
+	operation := "QUERY"
+	if fields := strings.Fields(sql); len(fields) != 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperation(operation), semconv.DBStatement(sql)))
+}
+
+// endStatement judges driver errors by what the repositories make of them.
+func endStatement(span trace.Span, err error) {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) {
+		span.End()
+		return
+	}
+	span.RecordError(err)
+	if models.AsError(database.Translate(err)) == models.ErrInternalServerError {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"technopark-dbms-forum/internal/database"
+)
+
+type fakeTx struct {
+	pgx.Tx
+}
+
+func (fakeTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag("DELETE 1"), nil
+}
+
+func (fakeTx) Commit(ctx context.Context) error {
+	return nil
+}
+
+type fakeConn struct {
+	database.Conn
+}
+
+func (fakeConn) Begin(ctx context.Context) (pgx.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func TestTransactionStatementsGetSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx := context.Background()
+	tx, err := Conn(fakeConn{}).Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM post WHERE id=$1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "DELETE" {
+		t.Fatalf("got %d spans, want the DELETE one", len(spans))
+	}
+}
@@ -0,0 +1,132 @@
+// Package tracing sets up OpenTelemetry and starts spans for requests and
+// SQL statements.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"technopark-dbms-forum/configs"
+	"technopark-dbms-forum/internal/logging"
+	"technopark-dbms-forum/models"
+)
+
+const serviceName = "forum"
+
+var tracer = otel.Tracer("technopark-dbms-forum/internal/tracing")
+
+// Setup installs the global tracer provider. With the "none" exporter spans
+// cost next to nothing and go nowhere. The returned func flushes pending
+// spans.
+func Setup(ctx context.Context, cfg configs.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// End finishes span. Any error is recorded, but only internal ones mark the
+// span failed: a missing thread or a conflict is a regular answer.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if models.AsError(err) == models.ErrInternalServerError {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Middleware starts a server span per request named after its route
+// template, continuing the trace of an incoming traceparent header.
+func Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				path = r.URL.Path
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method+" "+path,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(r.Method),
+					semconv.HTTPRoute(path),
+					attribute.String("route", route.GetName()),
+				))
+			defer span.End()
+			if id := logging.RequestID(ctx); id != "" {
+				span.SetAttributes(attribute.String("request_id", id))
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPStatusCode(rec.status))
+			if rec.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rec.status))
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"technopark-dbms-forum/models"
+)
+
+// Rules are declared in `validate` struct tags, comma separated:
+//
+//	required   value must not be empty
+//	email      looks like an e-mail address
+//	nickname   latin letters, digits, '_' and '.'
+//	slug       letters, digits, '-' and '_' with at least one non-digit
+//	oneof=a b  value is one of the space separated options
+//	min=n      non-empty string is at least n characters long
+//	max=n      string is at most n characters long
+//	maxbytes=n string is at most n bytes long in UTF-8
+//
+// Format rules skip empty strings, emptiness is the business of required.
+type rule func(value reflect.Value, arg string) string
+
+var (
+	emailPattern    = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+	nicknamePattern = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	slugPattern     = regexp.MustCompile(`^[\w-]*[A-Za-z_-][\w-]*$`)
+)
+
+var rules = map[string]rule{
+	"required": func(value reflect.Value, _ string) string {
+		if value.IsZero() || (value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "") {
+			return "is required"
+		}
+		return ""
+	},
+	"email":    pattern(emailPattern, "must be a valid e-mail address"),
+	"nickname": pattern(nicknamePattern, "may contain only latin letters, digits, '_' and '.'"),
+	"slug":     pattern(slugPattern, "may contain only letters, digits, '-' and '_' and must not be a number"),
+	"oneof": func(value reflect.Value, arg string) string {
+		if value.Kind() == reflect.String && value.String() == "" {
+			return ""
+		}
+		actual := fmt.Sprint(value.Interface())
+		for _, option := range strings.Fields(arg) {
+			if actual == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(arg), ", ")
+	},
+	"min": func(value reflect.Value, arg string) string {
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			panic("validation: bad min argument " + arg)
+		}
+		if value.Kind() == reflect.String && value.String() != "" && len([]rune(value.String())) < limit {
+			return fmt.Sprintf("must be at least %d characters long", limit)
+		}
+		return ""
+	},
+	"max": func(value reflect.Value, arg string) string {
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			panic("validation: bad max argument " + arg)
+		}
+		if value.Kind() == reflect.String && len([]rune(value.String())) > limit {
+			return fmt.Sprintf("must be at most %d characters long", limit)
+		}
+		return ""
+	},
+	"maxbytes": func(value reflect.Value, arg string) string {
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			panic("validation: bad maxbytes argument " + arg)
+		}
+		if value.Kind() == reflect.String && len(value.String()) > limit {
+			return fmt.Sprintf("must be at most %d bytes long", limit)
+		}
+		return ""
+	},
+}
+
+// Struct checks every rule declared on v, which may be a struct, a pointer
+// to one or a slice of them.
+func Struct(v interface{}) []models.FieldError {
+	return check(reflect.ValueOf(v), "", false)
+}
+
+// Partial is Struct without the required rule, for updates where an empty
+// field means "leave unchanged".
+func Partial(v interface{}) []models.FieldError {
+	return check(reflect.ValueOf(v), "", true)
+}
+
+func check(value reflect.Value, prefix string, partial bool) []models.FieldError {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	var result []models.FieldError
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			result = append(result, check(value.Index(i), fmt.Sprintf("%s[%d].", prefix, i), partial)...)
+		}
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			tag := field.Tag.Get("validate")
+			if field.Anonymous && tag == "" {
+				result = append(result, check(value.Field(i), prefix, partial)...)
+				continue
+			}
+			if tag == "" {
+				continue
+			}
+			for _, declared := range strings.Split(tag, ",") {
+				name, arg := declared, ""
+				if eq := strings.Index(declared, "="); eq != -1 {
+					name, arg = declared[:eq], declared[eq+1:]
+				}
+				if partial && name == "required" {
+					continue
+				}
+				validate, ok := rules[name]
+				if !ok {
+					panic("validation: unknown rule " + name)
+				}
+				if message := validate(value.Field(i), arg); message != "" {
+					result = append(result, models.FieldError{Field: prefix + fieldName(field), Message: message})
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func pattern(re *regexp.Regexp, message string) rule {
+	return func(value reflect.Value, _ string) string {
+		if value.Kind() != reflect.String || value.String() == "" {
+			return ""
+		}
+		if !re.MatchString(value.String()) {
+			return message
+		}
+		return ""
+	}
+}
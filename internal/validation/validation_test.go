@@ -0,0 +1,35 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"technopark-dbms-forum/models"
+)
+
+func TestRegistrationPasswordBytes(t *testing.T) {
+	user := models.User{Nickname: "jack", FullName: "Jack Sparrow", Email: "jack@sea.org"}
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"no password", "", true},
+		{"short", "1234567", false},
+		{"72 ASCII bytes", strings.Repeat("a", 72), true},
+		{"73 ASCII bytes", strings.Repeat("a", 73), false},
+		// 40 characters but 80 bytes: bcrypt would refuse it.
+		{"multibyte over 72 bytes", strings.Repeat("я", 40), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := Struct(models.Registration{User: user, Password: test.password})
+			if valid := len(fields) == 0; valid != test.valid {
+				t.Fatalf("valid = %v, want %v: %v", valid, test.valid, fields)
+			}
+			if !test.valid && fields[0].Field != "password" {
+				t.Fatalf("reported %s, want password", fields[0].Field)
+			}
+		})
+	}
+}
@@ -1,51 +1,121 @@
 package models
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 )
 
+// Error is the domain error returned by every layer. Code is stable and meant
+// for clients to branch on, Message is for humans and Details names the
+// offending entity (nickname, slug, id...).
+type Error struct {
+	Code    string            `json:"code"`
+	Status  int               `json:"-"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+	Fields  []FieldError      `json:"fields,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 var (
-	ErrBadRequest          = errors.New("Bad request")
-	ErrNotFound            = errors.New("Your requested item is not found")
-	ErrConflict            = errors.New("Your item has already exist")
-	ErrUnauthorized        = errors.New("User not authorised or not found")
-	ErrInternalServerError = errors.New("Internal Server Error")
+	ErrBadRequest          = &Error{Code: "bad_request", Status: http.StatusBadRequest, Message: "Bad request"}
+	ErrNotFound            = &Error{Code: "not_found", Status: http.StatusNotFound, Message: "Your requested item is not found"}
+	ErrConflict            = &Error{Code: "conflict", Status: http.StatusConflict, Message: "Your item has already exist"}
+	ErrUnauthorized        = &Error{Code: "unauthorized", Status: http.StatusUnauthorized, Message: "User not authorised or not found"}
+	ErrForbidden           = &Error{Code: "forbidden", Status: http.StatusForbidden, Message: "You are not allowed to do this"}
+	ErrValidation          = &Error{Code: "validation_failed", Status: http.StatusBadRequest, Message: "Request validation failed"}
+	ErrInvalidCursor       = &Error{Code: "invalid_cursor", Status: http.StatusBadRequest, Message: "Cursor is malformed, forged or issued for another listing"}
+	ErrPayloadTooLarge     = &Error{Code: "payload_too_large", Status: http.StatusRequestEntityTooLarge, Message: "Request body is too large"}
+	ErrKeyReused           = &Error{Code: "idempotency_key_reused", Status: http.StatusUnprocessableEntity, Message: "Idempotency key was used for a different request"}
+	ErrTooManyRequests     = &Error{Code: "rate_limited", Status: http.StatusTooManyRequests, Message: "Too many requests"}
+	ErrTimeout             = &Error{Code: "timeout", Status: http.StatusGatewayTimeout, Message: "Request deadline exceeded"}
+	ErrInternalServerError = &Error{Code: "internal", Status: http.StatusInternalServerError, Message: "Internal Server Error"}
 )
 
-func GetStatusCodePost(err error) int {
-	if err == nil {
-		return http.StatusCreated
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports errors of the same code as equal, so errors.Is(err, ErrNotFound)
+// holds for every not-found error whatever its message and details.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) WithMessage(format string, args ...interface{}) *Error {
+	clone := e.clone()
+	clone.Message = fmt.Sprintf(format, args...)
+	return clone
+}
+
+func (e *Error) WithDetail(key, value string) *Error {
+	clone := e.clone()
+	clone.Details[key] = value
+	return clone
+}
+
+func (e *Error) WithFields(fields []FieldError) *Error {
+	clone := e.clone()
+	clone.Fields = append(clone.Fields, fields...)
+	return clone
+}
+
+func (e *Error) clone() *Error {
+	clone := *e
+	clone.Details = make(map[string]string, len(e.Details)+1)
+	for key, value := range e.Details {
+		clone.Details[key] = value
 	}
+	clone.Fields = append([]FieldError(nil), e.Fields...)
+	return &clone
+}
 
-	switch err {
-	case ErrBadRequest: // 400
-		return http.StatusBadRequest
-	case ErrNotFound:
-		return http.StatusNotFound // 404
-	case ErrConflict:
-		return http.StatusConflict // 409
-	case ErrUnauthorized:
-		return http.StatusUnauthorized // 401
+// AsError converts any error into a domain error. Errors that are not domain
+// errors become ErrInternalServerError so driver messages never reach clients.
+func AsError(err error) *Error {
+	var domainErr *Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &domainErr):
+		return domainErr
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
 	default:
-		return http.StatusInternalServerError // 500
+		return ErrInternalServerError
 	}
 }
 
-func GetStatusCodeGet(err error) int {
+func StatusCode(err error) int {
 	if err == nil {
 		return http.StatusOK
 	}
-	switch err {
-	case ErrNotFound:
-		return http.StatusNotFound // 404
-	case ErrConflict:
-		return http.StatusConflict // 409
-	default:
-		return http.StatusInternalServerError // 500
-	}
+	return AsError(err).Status
 }
 
-type Error struct {
-	Message string `json:"message"`
-}
\ No newline at end of file
+func UserNotFound(nickname string) *Error {
+	return ErrNotFound.WithMessage("Can't find user by nickname: %s", nickname).WithDetail("nickname", nickname)
+}
+
+func ForumNotFound(slug string) *Error {
+	return ErrNotFound.WithMessage("Can't find forum by slug: %s", slug).WithDetail("slug", slug)
+}
+
+func ThreadNotFound(slugOrId string) *Error {
+	return ErrNotFound.WithMessage("Can't find thread by slug or id: %s", slugOrId).WithDetail("thread", slugOrId)
+}
+
+func PostNotFound(id int) *Error {
+	return ErrNotFound.WithMessage("Can't find post with id: %d", id).WithDetail("id", fmt.Sprint(id))
+}
+
+func RevisionNotFound(post int, id int) *Error {
+	return ErrNotFound.WithMessage("Can't find revision %d of post %d", id, post).WithDetail("revision", fmt.Sprint(id))
+}
@@ -4,16 +4,16 @@ import (
 	"database/sql"
 	"encoding/json"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/pgtype"
+	"github.com/jackc/pgtype"
 	"time"
 )
 
 type Forum struct {
 	ID      int    `json:"-"`
 	UserId  int    `json:"-"`
-	Title   string `json:"title"`
-	User    string `json:"user"`
-	Slug    string `json:"slug"`
+	Title   string `json:"title" validate:"required,max=256"`
+	User    string `json:"user" validate:"required,nickname"`
+	Slug    string `json:"slug" validate:"required,slug"`
 	Posts   int    `json:"posts"`
 	Threads int    `json:"threads"`
 }
@@ -32,13 +32,13 @@ type ThreadOut struct {
 
 type Thread struct {
 	Id      int       `json:"id"`
-	Title   string    `json:"title"`
-	Author  string    `json:"author"`
+	Title   string    `json:"title" validate:"required,max=256"`
+	Author  string    `json:"author" validate:"required,nickname"`
 	AuthorId  int    `json:"-"`
-	Forum   string    `json:"forum"`
-	Message string    `json:"message"`
+	Forum   string    `json:"forum" validate:"slug"`
+	Message string    `json:"message" validate:"required"`
 	Votes   int       `json:"votes"`
-	Slug    string    `json:"slug"`
+	Slug    string    `json:"slug" validate:"slug"`
 	Created time.Time `json:"created"`
 }
 
@@ -58,41 +58,158 @@ type ThreadIn struct {
 
 type User struct {
 	ID       int    `json:"-"`
-	Nickname string `json:"nickname"`
-	FullName string `json:"fullname"`
+	Nickname string `json:"nickname" validate:"required,nickname"`
+	FullName string `json:"fullname" validate:"required,max=256"`
 	About    string `json:"about"`
-	Email    string `json:"email"`
+	Email    string `json:"email" validate:"required,email"`
+	// PasswordHash is the bcrypt hash, empty for users that cannot log in.
+	PasswordHash string `json:"-"`
+}
+
+// Registration is the body of user creation. The password is optional so
+// clients that never log in keep working. bcrypt takes at most 72 bytes.
+type Registration struct {
+	User
+	Password string `json:"password,omitempty" validate:"min=8,maxbytes=72"`
+}
+
+type Credentials struct {
+	Nickname string `json:"nickname" validate:"required,nickname"`
+	Password string `json:"password" validate:"required"`
+}
+
+type Session struct {
+	Token    string    `json:"token"`
+	Nickname string    `json:"nickname"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Role is what a user may do in a forum beyond acting as themselves.
+type Role string
+
+const (
+	RoleNone      Role = ""
+	RoleModerator Role = "moderator"
+	RoleOwner     Role = "owner"
+	RoleAdmin     Role = "admin"
+)
+
+type Moderator struct {
+	Forum       string    `json:"forum"`
+	Nickname    string    `json:"nickname"`
+	AppointedBy string    `json:"appointedBy"`
+	Created     time.Time `json:"created"`
 }
 
 type Post struct {
 	ID       int              `json:"id"`
-	Author   string           `json:"author"`
+	Author   string           `json:"author" validate:"required,nickname"`
 	AuthorId int              `json:"-"`
 	Created  time.Time        `json:"created"`
 	Forum    string           `json:"forum"`
 	IsEdited bool             `json:"isEdited"`
-	Message  string           `json:"message"`
+	Message  string           `json:"message" validate:"required"`
 	Parent   JsonNullInt64    `json:"parent"`
 	Thread   int              `json:"thread,"`
 	Path     pgtype.Int8Array `json:"-"`
+	// IsDeleted posts keep their place in the tree, their message reads
+	// "[deleted]".
+	IsDeleted bool `json:"isDeleted,omitempty"`
+}
+
+// Search is a full-text query with its filters. Thread is a slug or id and
+// is resolved into ThreadId; zero From and To leave the range open.
+type Search struct {
+	Query    string    `json:"q" validate:"required,max=256"`
+	Type     string    `json:"type" validate:"oneof=post thread"`
+	Forum    string    `json:"-"`
+	Author   string    `json:"author" validate:"nickname"`
+	Thread   string    `json:"thread"`
+	ThreadId int       `json:"-"`
+	From     time.Time `json:"-"`
+	To       time.Time `json:"-"`
+}
+
+// SearchResult is a post or a thread matching a search. Snippet highlights
+// the matched words with <b> tags.
+type SearchResult struct {
+	Type    string    `json:"type"`
+	ID      int       `json:"id"`
+	Thread  int       `json:"thread"`
+	Forum   string    `json:"forum"`
+	Author  string    `json:"author"`
+	Created time.Time `json:"created"`
+	Title   string    `json:"title,omitempty"`
+	Snippet string    `json:"snippet"`
+	Rank    float32   `json:"rank"`
+}
+
+// Notification tells a user about a reply to their post or a mention of
+// their @nickname. Kind is "reply" or "mention".
+type Notification struct {
+	ID      int       `json:"id"`
+	Kind    string    `json:"kind"`
+	Post    int       `json:"post"`
+	Thread  int       `json:"thread"`
+	Forum   string    `json:"forum"`
+	Author  string    `json:"author"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+	Read    bool      `json:"read"`
+}
+
+type Unread struct {
+	Unread int `json:"unread"`
 }
 
 type Status struct {
 	User   int `json:"user"`
 	Forum  int `json:"forum"`
-	Thread int `json:"thread"'`
+	Thread int `json:"thread"`
 	Post   int `json:"post"`
+	Vote   int `json:"vote"`
+	// Forums is filled on request only, it is a row per forum.
+	Forums []ForumStatus `json:"forums,omitempty"`
+}
+
+// ClearedForum counts what clearing a forum removed. Users keeps its
+// meaning of the forum's user list: the users themselves stay.
+type ClearedForum struct {
+	Forum   string `json:"forum"`
+	Threads int    `json:"threads"`
+	Posts   int    `json:"posts"`
+	Votes   int    `json:"votes"`
+	Users   int    `json:"users"`
+}
+
+// ForumStatus mirrors the forum counters: deleted threads and posts are not
+// counted.
+type ForumStatus struct {
+	Slug    string `json:"slug"`
+	Threads int    `json:"threads"`
+	Posts   int    `json:"posts"`
 }
 
 type Vote struct {
-	Nickname string `json:"nickname"`
-	Voice    int    `json:"voice"`
+	Nickname string `json:"nickname" validate:"required,nickname"`
+	Voice    int    `json:"voice" validate:"oneof=-1 1"`
 	Thread   int    `json:"-"`
 }
 
 type PostUpdate struct {
 	ID       int       `json:"-"`
 	Message string `json:"message"`
+	// Editor is recorded on the revision the update creates.
+	Editor string `json:"-"`
+}
+
+// PostRevision is a message a post had before an edit replaced it.
+type PostRevision struct {
+	ID      int       `json:"id"`
+	Post    int       `json:"post"`
+	Editor  string    `json:"editor,omitempty"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
 }
 
 type PostFull struct {
@@ -100,12 +217,29 @@ type PostFull struct {
 	Forum  *Forum      `json:"forum"`
 	Post   *Post       `json:"post"`
 	Thread interface{} `json:"thread"`
+	// Revisions counts past edits, it is set for related=revisions.
+	Revisions *int `json:"revisions,omitempty"`
 }
 
 type Parameters struct {
 	Limit int    `json:"limit"`
 	Since string `json:"since"`
 	Desc  bool   `json:"desc"`
+	// Cursor continues a listing after the row it points at; Since is
+	// ignored when it is set.
+	Cursor *Cursor `json:"-"`
+}
+
+// Cursor is the signed continuation token of a listing. Scope binds it to the
+// listing it was issued for, Key is the sort key of the row to continue from
+// and Backward pages towards the start of the listing.
+type Cursor struct {
+	Scope    string   `json:"s"`
+	Sort     string   `json:"o,omitempty"`
+	Desc     bool     `json:"d,omitempty"`
+	Limit    int      `json:"l"`
+	Key      []string `json:"k"`
+	Backward bool     `json:"b,omitempty"`
 }
 
 type JsonNullInt64 struct {